@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Formatter renders a StructuredLog into the bytes an Appender writes out
+type Formatter interface {
+	Format(log *StructuredLog) ([]byte, error)
+}
+
+// FormatterFunc allows a plain function to be used as a Formatter
+type FormatterFunc func(log *StructuredLog) ([]byte, error)
+
+// Format calls f(log)
+func (f FormatterFunc) Format(log *StructuredLog) ([]byte, error) {
+	return f(log)
+}
+
+// JSONFormatter renders the StructuredLog as a single line of json, the default mango output
+var JSONFormatter Formatter = FormatterFunc(func(log *StructuredLog) ([]byte, error) {
+	return json.Marshal(log)
+})
+
+// Appender is a single output of the MangoLogger.
+// Each appender has its own enabled flag, minimum level and formatter and any number of them can be registered on a logger.
+type Appender interface {
+	// Enabled reports whether the appender is switched on at all
+	Enabled() bool
+
+	// Level is the minimum level a record needs to reach this appender
+	Level() slog.Level
+
+	// Formatter renders the StructuredLog before it is passed to Append
+	Formatter() Formatter
+
+	// Append writes out the formatted log entry
+	Append(log *StructuredLog, formatted []byte) error
+}
+
+// WriterAppender is a generic Appender writing each formatted entry, newline terminated, to an io.Writer
+type WriterAppender struct {
+	writer    io.Writer
+	level     slog.Leveler
+	formatter Formatter
+	enabled   atomic.Bool
+	mu        sync.Mutex
+}
+
+// NewWriterAppender creates an enabled WriterAppender.
+// A nil level defaults to slog.LevelInfo and a nil formatter defaults to JSONFormatter.
+func NewWriterAppender(writer io.Writer, level slog.Leveler, formatter Formatter) *WriterAppender {
+	if level == nil {
+		level = slog.LevelInfo
+	}
+	if formatter == nil {
+		formatter = JSONFormatter
+	}
+	appender := &WriterAppender{
+		writer:    writer,
+		level:     level,
+		formatter: formatter,
+	}
+	appender.enabled.Store(true)
+	return appender
+}
+
+// SetEnabled switches the appender on or off, safe to call while logging
+func (a *WriterAppender) SetEnabled(enabled bool) {
+	a.enabled.Store(enabled)
+}
+
+func (a *WriterAppender) Enabled() bool {
+	return a.enabled.Load()
+}
+
+func (a *WriterAppender) Level() slog.Level {
+	return a.level.Level()
+}
+
+func (a *WriterAppender) Formatter() Formatter {
+	return a.formatter
+}
+
+func (a *WriterAppender) Append(_ *StructuredLog, formatted []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err := a.writer.Write(append(formatted, '\n'))
+	return err
+}
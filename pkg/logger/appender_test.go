@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingAppender always fails to append
+type failingAppender struct{}
+
+func (failingAppender) Enabled() bool        { return true }
+func (failingAppender) Level() slog.Level    { return slog.LevelDebug }
+func (failingAppender) Formatter() Formatter { return JSONFormatter }
+func (failingAppender) Append(*StructuredLog, []byte) error {
+	return errors.New("append failed")
+}
+
+func TestMangoLogger_AddAppender(t *testing.T) {
+	logger := newTestLogger(false, false, false, true)
+	var buf bytes.Buffer
+	logger.AddAppender(NewWriterAppender(&buf, slog.LevelInfo, nil))
+	assert.Len(t, logger.Appenders(), 4)
+
+	l := slog.New(logger)
+	l.Debug("below threshold")
+	l.Info("custom appender", slog.String("k", "v"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 1)
+
+	var log StructuredLog
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &log))
+	assert.Equal(t, "custom appender", log.Message)
+	assert.Equal(t, map[string]interface{}{"k": "v"}, log.Attributes.Map())
+}
+
+func TestMangoLogger_OnlyCustomAppenderEnabled(t *testing.T) {
+	logger := newTestLogger(false, false, false, true)
+	var buf bytes.Buffer
+	appender := NewWriterAppender(&buf, slog.LevelDebug, FormatterFunc(func(log *StructuredLog) ([]byte, error) {
+		return []byte(log.Level.String() + " " + log.Message.(string)), nil
+	}))
+	logger.AddAppender(appender)
+
+	err := logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelDebug, "first", 0))
+	assert.NoError(t, err)
+
+	appender.SetEnabled(false)
+	err = logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "second", 0))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "DEBUG first\n", buf.String())
+}
+
+func TestMangoLogger_AppenderErrorsAreJoined(t *testing.T) {
+	logger := newTestLogger(false, false, false, true)
+	var buf bytes.Buffer
+	logger.AddAppender(failingAppender{}, NewWriterAppender(&buf, nil, nil))
+
+	err := logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "still written", 0))
+	assert.ErrorContains(t, err, "append failed")
+	assert.Contains(t, buf.String(), "still written")
+}
+
+func TestMangoLogger_FormatterError(t *testing.T) {
+	logger := newTestLogger(false, false, false, true)
+	var buf bytes.Buffer
+	logger.AddAppender(NewWriterAppender(&buf, nil, FormatterFunc(func(*StructuredLog) ([]byte, error) {
+		return nil, errors.New("format failed")
+	})))
+
+	err := logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "not written", 0))
+	assert.ErrorContains(t, err, "format failed")
+	assert.Empty(t, buf.String())
+}
+
+func TestBuiltInAppenders_Levels(t *testing.T) {
+	logger := newTestLogger(true, true, false, true)
+	assert.Equal(t, slog.LevelDebug, cliAppenderOf(logger).Level()) // verbose
+	assert.Equal(t, slog.LevelInfo, fileAppenderOf(logger).Level())
+	assert.Equal(t, slog.LevelDebug, logger.appenders[2].Level())
+
+	// the levels follow the config changes
+	logger.Config.Out.Cli.Verbose = false
+	logger.Config.Out.File.Debug = true
+	logger.Config.Out.Syslog.Level = "WARN"
+	assert.Equal(t, slog.LevelInfo, cliAppenderOf(logger).Level())
+	assert.Equal(t, slog.LevelDebug, fileAppenderOf(logger).Level())
+	assert.Equal(t, slog.LevelWarn, logger.appenders[2].Level())
+
+	logger.Config.Out.File.Level = "ERROR"
+	assert.Equal(t, slog.LevelError, fileAppenderOf(logger).Level()) // Level overrides Debug
+
+	// a level set at runtime holds until the config changes
+	logger.OutputLevel(OutputFile).Set(slog.LevelWarn)
+	assert.Equal(t, slog.LevelWarn, fileAppenderOf(logger).Level())
+	logger.Config.Out.File.Level = ""
+	assert.Equal(t, slog.LevelDebug, fileAppenderOf(logger).Level())
+
+	syslog := logger.appenders[2]
+	assert.False(t, syslog.Enabled())
+	logger.Config.Out.Syslog.Facility = SyslogFacilityLocal0
+	assert.True(t, syslog.Enabled())
+}
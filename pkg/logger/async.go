@@ -0,0 +1,207 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrLoggerClosed is returned when logging through a MangoLogger that has been closed
+var ErrLoggerClosed = errors.New("mango logger is closed")
+
+// asyncEntry is a built log waiting to be written by the async worker, or a flush marker when flushed is set
+type asyncEntry struct {
+	log       *StructuredLog
+	appenders []Appender
+	flushed   chan struct{}
+}
+
+// asyncQueue is the bounded queue drained by a single background worker
+type asyncQueue struct {
+	config        *AsyncConfig
+	overflowLevel slog.Level
+	entries       chan asyncEntry
+	dropped       atomic.Uint64
+
+	// closeMu is held for reading while enqueueing so Close can safely close the entries channel
+	closeMu   sync.RWMutex
+	closeOnce sync.Once
+	// closing is closed when Close starts, rejecting new records and releasing the producers waiting for room
+	closing chan struct{}
+	// abortOnce closes abort when the Close context is done, the worker then discards the records left
+	abortOnce sync.Once
+	abort     chan struct{}
+
+	// flushesMu guards flushes, the flush markers dropped by OverflowDropOldest completed after the worker's current write
+	flushesMu sync.Mutex
+	flushes   []chan struct{}
+
+	workerDone chan struct{}
+}
+
+func newAsyncQueue(config *AsyncConfig, levels levelNames) *asyncQueue {
+	size := config.QueueSize
+	if size <= 0 {
+		size = DefaultAsyncQueueSize
+	}
+	overflowLevel, _ := levels.newLevelVar(config.OverflowLevel, slog.LevelInfo)
+	q := &asyncQueue{
+		config:        config,
+		overflowLevel: overflowLevel.Level(),
+		entries:       make(chan asyncEntry, size),
+		closing:       make(chan struct{}),
+		abort:         make(chan struct{}),
+		workerDone:    make(chan struct{}),
+	}
+	go q.work()
+	return q
+}
+
+func (q *asyncQueue) work() {
+	defer close(q.workerDone)
+	defer q.completeFlushes()
+	for entry := range q.entries {
+		if entry.flushed != nil {
+			close(entry.flushed)
+			continue
+		}
+		select {
+		case <-q.abort:
+			q.dropped.Add(1)
+			continue
+		default:
+		}
+		if err := write(entry.log, entry.appenders); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to write log entry %s: %v\n", entry.log.LogId, err)
+		}
+		q.completeFlushes()
+	}
+}
+
+// enqueue applies the overflow policy when the queue is full
+func (q *asyncQueue) enqueue(entry asyncEntry) error {
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+	select {
+	case <-q.closing:
+		return ErrLoggerClosed
+	default:
+	}
+	select {
+	case q.entries <- entry:
+		return nil
+	default:
+	}
+
+	switch q.config.Overflow {
+	case OverflowDropNewest:
+		q.dropped.Add(1)
+	case OverflowDropOldest:
+		for {
+			select {
+			case q.entries <- entry:
+				return nil
+			default:
+			}
+			select {
+			case oldest := <-q.entries:
+				q.discard(oldest)
+			default:
+			}
+		}
+	case OverflowDropBelowLevel:
+		if entry.log.Level < q.overflowLevel {
+			q.dropped.Add(1)
+			return nil
+		}
+		return q.wait(entry)
+	default:
+		return q.wait(entry)
+	}
+	return nil
+}
+
+// wait blocks until there is room for the entry, the record being dropped when Close starts meanwhile
+func (q *asyncQueue) wait(entry asyncEntry) error {
+	select {
+	case q.entries <- entry:
+		return nil
+	case <-q.closing:
+		q.dropped.Add(1)
+		return ErrLoggerClosed
+	}
+}
+
+// discard drops the oldest entry, a flush marker being completed once the worker is done with its current write
+func (q *asyncQueue) discard(oldest asyncEntry) {
+	if oldest.flushed == nil {
+		q.dropped.Add(1)
+		return
+	}
+	q.flushesMu.Lock()
+	defer q.flushesMu.Unlock()
+	q.flushes = append(q.flushes, oldest.flushed)
+}
+
+func (q *asyncQueue) completeFlushes() {
+	q.flushesMu.Lock()
+	defer q.flushesMu.Unlock()
+	for _, flushed := range q.flushes {
+		close(flushed)
+	}
+	q.flushes = nil
+}
+
+// flush waits until every entry queued before it is written, or the context is done
+// A marker is queued behind them, so records logged meanwhile by other goroutines don't delay it
+func (q *asyncQueue) flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	if err := q.enqueueFlush(ctx, flushed); err != nil {
+		return err
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *asyncQueue) enqueueFlush(ctx context.Context, flushed chan struct{}) error {
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+	select {
+	case q.entries <- asyncEntry{flushed: flushed}:
+		return nil
+	case <-q.closing:
+		// the queue is drained by Close
+		close(flushed)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close stops accepting entries and waits for the worker to drain the queue, or returns when the context is done
+// The worker then discards the entries left, only the write in progress (if any) completing after Close returns
+func (q *asyncQueue) close(ctx context.Context) error {
+	q.closeOnce.Do(func() {
+		close(q.closing)
+		// the producers holding closeMu no longer block once closing is closed
+		q.closeMu.Lock()
+		close(q.entries)
+		q.closeMu.Unlock()
+	})
+
+	select {
+	case <-q.workerDone:
+		return nil
+	case <-ctx.Done():
+		q.abortOnce.Do(func() { close(q.abort) })
+		return ctx.Err()
+	}
+}
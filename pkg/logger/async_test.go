@@ -0,0 +1,246 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gatedAppender blocks every Append until the gate is opened, recording the messages written
+type gatedAppender struct {
+	started  chan struct{}
+	gate     chan struct{}
+	mu       sync.Mutex
+	messages []string
+}
+
+func newGatedAppender() *gatedAppender {
+	return &gatedAppender{started: make(chan struct{}, 100), gate: make(chan struct{})}
+}
+
+func (a *gatedAppender) Enabled() bool        { return true }
+func (a *gatedAppender) Level() slog.Level    { return slog.LevelDebug }
+func (a *gatedAppender) Formatter() Formatter { return JSONFormatter }
+func (a *gatedAppender) Append(log *StructuredLog, _ []byte) error {
+	select {
+	case a.started <- struct{}{}:
+	default:
+	}
+	<-a.gate
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messages = append(a.messages, log.Message.(string))
+	return nil
+}
+
+func (a *gatedAppender) written() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.messages
+}
+
+func newAsyncTestLogger(async *AsyncConfig) (*MangoLogger, *gatedAppender) {
+	logger := newTestLogger(false, false, false, true)
+	logger.Config.Out.Async = async
+	logger = NewMangoLogger(logger.Config)
+	appender := newGatedAppender()
+	logger.AddAppender(appender)
+	return logger, appender
+}
+
+func handleMessage(t *testing.T, logger *MangoLogger, level slog.Level, msg string) {
+	err := logger.Handle(context.Background(), slog.NewRecord(time.Now(), level, msg, 0))
+	assert.NoError(t, err)
+}
+
+// fillQueue logs "1", waits for the worker to pick it up and then fills a queue of size 1 with "2"
+func fillQueue(t *testing.T, logger *MangoLogger, appender *gatedAppender) {
+	handleMessage(t, logger, slog.LevelInfo, "1")
+	<-appender.started
+	handleMessage(t, logger, slog.LevelInfo, "2")
+}
+
+func TestAsync_FlushWritesEverything(t *testing.T) {
+	logger, appender := newAsyncTestLogger(&AsyncConfig{Enabled: true})
+	close(appender.gate)
+
+	for _, msg := range []string{"a", "b", "c"} {
+		handleMessage(t, logger, slog.LevelInfo, msg)
+	}
+
+	assert.NoError(t, logger.Flush(context.Background()))
+	assert.Equal(t, []string{"a", "b", "c"}, appender.written())
+	assert.NoError(t, logger.Close(context.Background()))
+}
+
+func TestAsync_OverflowPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *AsyncConfig
+		level    slog.Level
+		expected []string
+		dropped  uint64
+	}{
+		{"drop newest", &AsyncConfig{Enabled: true, QueueSize: 1, Overflow: OverflowDropNewest}, slog.LevelInfo, []string{"1", "2"}, 1},
+		{"drop oldest", &AsyncConfig{Enabled: true, QueueSize: 1, Overflow: OverflowDropOldest}, slog.LevelInfo, []string{"1", "3"}, 1},
+		{"drop below level", &AsyncConfig{Enabled: true, QueueSize: 1, Overflow: OverflowDropBelowLevel, OverflowLevel: "INFO"}, slog.LevelDebug, []string{"1", "2"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, appender := newAsyncTestLogger(tt.config)
+			fillQueue(t, logger, appender)
+
+			handleMessage(t, logger, tt.level, "3")
+			assert.Equal(t, tt.dropped, logger.Dropped())
+
+			close(appender.gate)
+			assert.NoError(t, logger.Close(context.Background()))
+			assert.Equal(t, tt.expected, appender.written())
+		})
+	}
+}
+
+func TestAsync_OverflowBlock(t *testing.T) {
+	logger, appender := newAsyncTestLogger(&AsyncConfig{Enabled: true, QueueSize: 1, Overflow: OverflowDropBelowLevel, OverflowLevel: "INFO"})
+	fillQueue(t, logger, appender)
+
+	handled := make(chan struct{})
+	go func() {
+		handleMessage(t, logger, slog.LevelError, "3")
+		close(handled)
+	}()
+
+	select {
+	case <-handled:
+		t.Fatal("expected Handle to block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(appender.gate)
+	<-handled
+	assert.NoError(t, logger.Flush(context.Background()))
+	assert.Equal(t, []string{"1", "2", "3"}, appender.written())
+	assert.Zero(t, logger.Dropped())
+}
+
+func TestAsync_FlushHonoursContext(t *testing.T) {
+	logger, appender := newAsyncTestLogger(&AsyncConfig{Enabled: true})
+	handleMessage(t, logger, slog.LevelInfo, "stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, logger.Flush(ctx), context.DeadlineExceeded)
+
+	close(appender.gate)
+	assert.NoError(t, logger.Flush(context.Background()))
+}
+
+func TestAsync_HandleAfterClose(t *testing.T) {
+	logger, appender := newAsyncTestLogger(&AsyncConfig{Enabled: true})
+	close(appender.gate)
+	assert.NoError(t, logger.Close(context.Background()))
+	assert.NoError(t, logger.Close(context.Background()))
+
+	err := logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "late", 0))
+	assert.ErrorIs(t, err, ErrLoggerClosed)
+}
+
+func TestAsync_CloseHonoursContext(t *testing.T) {
+	logger, appender := newAsyncTestLogger(&AsyncConfig{Enabled: true, QueueSize: 1})
+	fillQueue(t, logger, appender)
+
+	// a producer blocked on the full queue is released and its record rejected
+	handled := make(chan error)
+	go func() {
+		handled <- logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "3", 0))
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.ErrorIs(t, logger.Close(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, <-handled, ErrLoggerClosed)
+
+	// the worker finishes its current write, discards the rest and exits
+	close(appender.gate)
+	<-logger.async.workerDone
+	assert.Equal(t, []string{"1"}, appender.written())
+	assert.Equal(t, uint64(2), logger.Dropped())
+	assert.NoError(t, logger.Close(context.Background()))
+}
+
+func TestAsync_FlushWhileLogging(t *testing.T) {
+	logger, appender := newAsyncTestLogger(&AsyncConfig{Enabled: true})
+	close(appender.gate)
+	handleMessage(t, logger, slog.LevelInfo, "before")
+
+	stop := make(chan struct{})
+	logging := make(chan struct{})
+	go func() {
+		defer close(logging)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				handleMessage(t, logger, slog.LevelInfo, "busy")
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, logger.Flush(ctx))
+	assert.Equal(t, "before", appender.written()[0])
+	close(stop)
+	<-logging
+	assert.NoError(t, logger.Close(context.Background()))
+}
+
+func TestAsync_FlushDroppedByOverflow(t *testing.T) {
+	logger, appender := newAsyncTestLogger(&AsyncConfig{Enabled: true, QueueSize: 1, Overflow: OverflowDropOldest})
+	handleMessage(t, logger, slog.LevelInfo, "1")
+	<-appender.started
+
+	// the flush marker queued behind "1" is pushed out by "2", completing once "1" is written
+	flushed := make(chan error)
+	go func() { flushed <- logger.Flush(context.Background()) }()
+	time.Sleep(20 * time.Millisecond)
+	handleMessage(t, logger, slog.LevelInfo, "2")
+	close(appender.gate)
+	assert.NoError(t, <-flushed)
+	assert.Equal(t, "1", appender.written()[0])
+	assert.NoError(t, logger.Close(context.Background()))
+}
+
+func TestAsync_CustomOverflowLevel(t *testing.T) {
+	logger := newTestLogger(false, false, false, true)
+	logger.Config.MangoConfig.LevelNames = map[string]int{"NOTICE": int(LevelNotice)}
+	logger.Config.Out.Async = &AsyncConfig{Enabled: true, QueueSize: 1, Overflow: OverflowDropBelowLevel, OverflowLevel: "notice"}
+	logger = NewMangoLogger(logger.Config)
+	appender := newGatedAppender()
+	logger.AddAppender(appender)
+	fillQueue(t, logger, appender)
+
+	handleMessage(t, logger, slog.LevelInfo, "3")
+	assert.Equal(t, uint64(1), logger.Dropped())
+	close(appender.gate)
+	assert.NoError(t, logger.Close(context.Background()))
+
+	config := &LogConfig{Out: &OutConfig{Async: &AsyncConfig{Enabled: true, OverflowLevel: "NOTICE"}}}
+	config.SetDefaults()
+	assert.ErrorContains(t, config.Validate(), `out.async.overflow-level: unknown log level "NOTICE"`)
+}
+
+func TestSync_FlushAndClose(t *testing.T) {
+	logger := newTestLogger(false, true, false, true)
+	assert.NoError(t, logger.Flush(context.Background()))
+	assert.Zero(t, logger.Dropped())
+	assert.NoError(t, logger.Close(context.Background()))
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// maxValueDepth bounds the conversion of nested maps and slices, guarding against cyclic values
+const maxValueDepth = 32
+
+// AttrValue converts a slog.Value to the value written in the log attributes:
+//   - LogValuers are resolved and groups become OrderedMaps (see ToAttributes)
+//   - durations are rendered as strings (1.5s) and times with RFC3339NanoMC
+//   - NaN and infinite floats as strings (NaN, +Inf, -Inf)
+//   - errors with ErrorValue, []byte as a string (base64 when not valid UTF-8),
+//     encoding.TextMarshaler and fmt.Stringer values as their text
+//   - json.Marshaler values, structs and other values as their json.RawMessage, marshalled once here rather than again by each output
+//   - values json can't marshal (channels, functions, cycles...) and panicking methods as a "!ERROR: ..." string,
+//     so a single bad value never fails the whole log
+func AttrValue(value slog.Value) any {
+	value = value.Resolve()
+	switch value.Kind() {
+	case slog.KindString:
+		return value.String()
+	case slog.KindInt64:
+		return value.Int64()
+	case slog.KindUint64:
+		return value.Uint64()
+	case slog.KindFloat64:
+		return floatValue(value.Float64())
+	case slog.KindBool:
+		return value.Bool()
+	case slog.KindDuration:
+		return value.Duration().String()
+	case slog.KindTime:
+		return value.Time().Format(RFC3339NanoMC)
+	case slog.KindGroup:
+		return ToAttributes(value.Group(), DuplicateLastWins)
+	}
+	return anyValue(value.Any(), 0)
+}
+
+func floatValue(f float64) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f
+}
+
+func anyValue(v any, depth int) (result any) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = fmt.Sprintf("!ERROR: %T panicked: %v", v, r)
+		}
+	}()
+
+	if depth > maxValueDepth {
+		return fmt.Sprintf("!ERROR: %T nested deeper than %d levels", v, maxValueDepth)
+	}
+	switch value := v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return v
+	case slog.Value:
+		return AttrValue(value)
+	case slog.LogValuer:
+		return AttrValue(slog.AnyValue(value))
+	case error:
+		return ErrorValue(value)
+	case time.Time:
+		return value.Format(RFC3339NanoMC)
+	case time.Duration:
+		return value.String()
+	case float64:
+		return floatValue(value)
+	case []byte:
+		if utf8.Valid(value) {
+			return string(value)
+		}
+		return base64.StdEncoding.EncodeToString(value)
+	case hashedSecret:
+		// hashed by the redactor
+		return value
+	case map[string]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			converted[key] = anyValue(item, depth+1)
+		}
+		return converted
+	case []interface{}:
+		converted := make([]interface{}, len(value))
+		for i, item := range value {
+			converted[i] = anyValue(item, depth+1)
+		}
+		return converted
+	case json.Marshaler:
+		// marshals itself, below
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return "!ERROR: " + err.Error()
+		}
+		return string(text)
+	case fmt.Stringer:
+		return value.String()
+	}
+	text, err := json.Marshal(v)
+	if err != nil {
+		return "!ERROR: " + err.Error()
+	}
+	return json.RawMessage(text)
+}
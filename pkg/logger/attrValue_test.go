@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type userValuer struct {
+	id   int
+	name string
+}
+
+func (u userValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", u.id), slog.String("name", u.name))
+}
+
+type level string
+
+func (l level) String() string { return "level-" + string(l) }
+
+type nilStringer struct{ name string }
+
+func (n *nilStringer) String() string { return n.name }
+
+type failingText struct{}
+
+func (failingText) MarshalText() ([]byte, error) { return nil, assert.AnError }
+
+func TestAttrValue(t *testing.T) {
+	ts := time.Date(2025, 1, 15, 9, 53, 34, 717000000, time.FixedZone("", -5*3600))
+	cyclic := map[string]interface{}{"name": "loop"}
+	cyclic["self"] = cyclic
+
+	attributes := ToMap([]slog.Attr{
+		slog.Any("user", userValuer{id: 7, name: "jane"}),
+		slog.Group("request", slog.Duration("latency", 1500*time.Millisecond), slog.Time("at", ts)),
+		slog.Any("timeout", 2*time.Second),
+		slog.Any("times", []interface{}{ts, time.Minute}),
+		slog.Any("payload", []byte("hello")),
+		slog.Any("binary", []byte{0xff, 0x00}),
+		slog.Any("ip", net.ParseIP("10.0.0.1")),
+		slog.Any("level", level("debug")),
+		slog.Float64("ratio", math.NaN()),
+		slog.Any("inf", math.Inf(1)),
+		slog.Any("nil", (*nilStringer)(nil)),
+		slog.Any("text", failingText{}),
+		slog.Any("channel", make(chan int)),
+		slog.Any("cyclic", cyclic),
+		slog.Any("raw", json.RawMessage(`{"a":1}`)),
+		slog.Uint64("count", 3),
+		slog.Bool("ok", true),
+	})
+
+	assert.Equal(t, map[string]interface{}{"id": int64(7), "name": "jane"}, attributes["user"])
+	assert.Equal(t, map[string]interface{}{"latency": "1.5s", "at": "2025-01-15T09:53:34.717-0500"}, attributes["request"])
+	assert.Equal(t, "2s", attributes["timeout"])
+	assert.Equal(t, []interface{}{"2025-01-15T09:53:34.717-0500", "1m0s"}, attributes["times"])
+	assert.Equal(t, "hello", attributes["payload"])
+	assert.Equal(t, "/wA=", attributes["binary"])
+	assert.Equal(t, "10.0.0.1", attributes["ip"])
+	assert.Equal(t, "level-debug", attributes["level"])
+	assert.Equal(t, "NaN", attributes["ratio"])
+	assert.Equal(t, "+Inf", attributes["inf"])
+	assert.Contains(t, attributes["nil"], "!ERROR: *logger.nilStringer panicked")
+	assert.Equal(t, "!ERROR: "+assert.AnError.Error(), attributes["text"])
+	assert.Equal(t, "!ERROR: json: unsupported type: chan int", attributes["channel"])
+	assert.Equal(t, json.RawMessage(`{"a":1}`), attributes["raw"])
+	assert.Equal(t, uint64(3), attributes["count"])
+	assert.Equal(t, true, attributes["ok"])
+
+	depth := 0
+	for value := attributes["cyclic"]; ; depth++ {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			assert.Contains(t, value, "nested deeper than")
+			break
+		}
+		value = nested["self"]
+	}
+	assert.Equal(t, maxValueDepth+1, depth)
+
+	// the attributes still marshal
+	_, err := json.Marshal(attributes)
+	assert.NoError(t, err)
+
+	// structs are marshalled once, ready to be written
+	assert.Equal(t, json.RawMessage(`{"id":1,"items":null,"tags":null}`), AttrValue(slog.AnyValue(benchmarkPayload{ID: 1})))
+	// plain values are kept as they are
+	assert.Equal(t, map[string]interface{}{"n": 1, "ok": true}, AttrValue(slog.AnyValue(map[string]interface{}{"n": 1, "ok": true})))
+}
+
+type benchmarkPayload struct {
+	ID    int               `json:"id"`
+	Items []string          `json:"items"`
+	Tags  map[string]string `json:"tags"`
+}
+
+// BenchmarkAttrValue converts a struct attribute and writes it, against the previous approach
+// checking the value with json.Marshal and marshalling it again when writing the log
+func BenchmarkAttrValue(b *testing.B) {
+	payload := benchmarkPayload{ID: 42, Items: []string{"apple", "pear", "plum"}, Tags: map[string]string{"tenant": "acme", "region": "eu"}}
+
+	b.Run("baseline", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := json.Marshal(payload); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := json.Marshal(map[string]interface{}{"payload": payload}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("current", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := json.Marshal(map[string]interface{}{"payload": AttrValue(slog.AnyValue(payload))}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
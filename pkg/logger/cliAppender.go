@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/itchyny/gojq"
+)
+
+// cliAppender is the built-in Appender printing to stdout/stderr as configured by CliConfig
+type cliAppender struct {
+	config   *CliConfig
+	level    *outputLevel
+	verbose  *gojq.Code
+	friendly *gojq.Code
+	encoder  Formatter
+
+	// stdout and stderr console formatters, used when Friendly without a FriendlyFormat
+	stdout *ConsoleFormatter
+	stderr *ConsoleFormatter
+}
+
+// newCliAppender compiles the verbose and friendly formats once, printing with CliConfig.Encoding instead of an invalid one
+func newCliAppender(config *CliConfig, level *outputLevel, levels levelNames, customFields []string) *cliAppender {
+	a := &cliAppender{config: config, level: level, encoder: newOutputEncoder(config.Encoding, config.FieldNames, customFields)}
+	a.verbose, _ = compileGoJQ(config.VerboseFormat)
+	if config.FriendlyFormat != "" {
+		a.friendly, _ = compileGoJQ(config.FriendlyFormat)
+	} else {
+		a.stdout = NewConsoleFormatter(shouldColor(config.Color, os.Stdout))
+		a.stderr = NewConsoleFormatter(shouldColor(config.Color, os.Stderr))
+		// the custom level names are padded too, keeping the columns aligned
+		a.stdout.levelWidth, a.stderr.levelWidth = levels.width(), levels.width()
+	}
+	return a
+}
+
+func (a *cliAppender) Enabled() bool {
+	return a.config.Enabled
+}
+
+// Level is CliConfig.Level, or DEBUG in verbose mode and INFO otherwise
+func (a *cliAppender) Level() slog.Level {
+	return a.level.Level()
+}
+
+func (a *cliAppender) Formatter() Formatter {
+	return FormatterFunc(a.format)
+}
+
+// format applies the verbose format to DEBUG and below and the friendly format (when enabled) to everything else
+// Everything else, and logs the format fails to compile or to evaluate against, is printed with CliConfig.Encoding
+func (a *cliAppender) format(log *StructuredLog) ([]byte, error) {
+	var code *gojq.Code
+	switch {
+	case log.Level < slog.LevelInfo:
+		code = a.verbose
+	case a.config.Friendly && a.config.FriendlyFormat == "":
+		if log.Level < slog.LevelWarn {
+			return a.stdout.Format(log)
+		}
+		return a.stderr.Format(log)
+	case a.config.Friendly:
+		code = a.friendly
+	}
+	if code != nil {
+		if result, err := formatWithGoJQ(code, log); err == nil {
+			return result, nil
+		}
+	}
+	return a.encoder.Format(log)
+}
+
+// Append prints levels below WARN to stdout, WARN and above to stderr
+func (a *cliAppender) Append(log *StructuredLog, formatted []byte) error {
+	if log.Level < slog.LevelWarn {
+		_, _ = fmt.Fprintln(os.Stdout, string(formatted))
+	} else {
+		_, _ = fmt.Fprintln(os.Stderr, string(formatted))
+	}
+	return nil
+}
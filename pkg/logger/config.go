@@ -34,6 +34,84 @@ const (
 	SyslogFacilityLocal7   = "local7"
 )
 
+type SyslogConfig struct {
+	// Facility refers to the syslog facility of a given log
+	Facility SyslogFacility `yaml:"facility" json:"facility"`
+
+	// Level is the minimum level sent to syslog - Defaults to DEBUG
+	// Either a slog level (INFO, debug-4, 12) or a name from MangoConfig.LevelNames
+	Level string `yaml:"level" json:"level"`
+
+	// Network used to reach the syslog daemon
+	// "udp", "tcp" and "tls" send to a remote collector, "unix"/"unixgram" to a local socket
+	// Leave Network and Address empty to use the local syslog daemon
+	// Only the remote transports are supported on windows
+	Network string `yaml:"network" json:"network"`
+
+	// Address of the syslog daemon, a socket path for unix networks or host:port otherwise
+	Address string `yaml:"address" json:"address"`
+
+	// TLS settings used when Network is "tls"
+	TLS *SyslogTLSConfig `yaml:"tls" json:"tls"`
+
+	// Format of the messages sent to a remote collector - Defaults to SyslogFormatRFC3164
+	Format SyslogFormat `yaml:"format" json:"format"`
+
+	// Framing of the messages over "tcp" and "tls" - Defaults to SyslogFramingOctetCounting
+	Framing SyslogFraming `yaml:"framing" json:"framing"`
+
+	// StructuredDataID is the SD-ID of the RFC 5424 structured data element - Defaults to DefaultSyslogStructuredDataID
+	StructuredDataID string `yaml:"structured-data-id" json:"structuredDataId"`
+
+	// Encoding of the message sent to syslog - Defaults to EncodingMangoJSON
+	Encoding Encoding `yaml:"encoding" json:"encoding"`
+
+	// FieldNames renames the StructuredLog fields in the syslog message, keyed by their mango json name (e.g. correlationid: trace.id)
+	FieldNames map[string]string `yaml:"field-names" json:"fieldNames"`
+}
+
+// SyslogFormat is the message format used for remote syslog
+type SyslogFormat string
+
+const (
+	// SyslogFormatRFC3164 is the BSD syslog format: <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+	SyslogFormatRFC3164 SyslogFormat = "rfc3164"
+
+	// SyslogFormatRFC5424 is the IETF syslog format with structured data populated from the StructuredLog
+	SyslogFormatRFC5424 SyslogFormat = "rfc5424"
+)
+
+// SyslogFraming is how messages are delimited on stream transports (tcp and tls)
+type SyslogFraming string
+
+const (
+	// SyslogFramingOctetCounting prefixes each message with its length (RFC 6587 3.4.1)
+	SyslogFramingOctetCounting SyslogFraming = "octet-counting"
+
+	// SyslogFramingNonTransparent terminates each message with a new line (RFC 6587 3.4.2)
+	SyslogFramingNonTransparent SyslogFraming = "non-transparent"
+)
+
+// DefaultSyslogStructuredDataID is the SD-ID used for the mango fields in RFC 5424 messages
+// 32473 is the private enterprise number reserved for documentation (RFC 5612)
+const DefaultSyslogStructuredDataID = "mango@32473"
+
+// SyslogTLSConfig holds the TLS settings for remote syslog
+type SyslogTLSConfig struct {
+	// CAFile is a PEM bundle of the CAs trusted to sign the collector's certificate - Defaults to the system pool
+	CAFile string `yaml:"ca-file" json:"caFile"`
+
+	// CertFile and KeyFile are the PEM client certificate and key, for collectors requiring mutual TLS
+	CertFile string `yaml:"cert-file" json:"certFile"`
+	KeyFile  string `yaml:"key-file" json:"keyFile"`
+
+	// ServerName overrides the name verified against the collector's certificate - Defaults to the Address host
+	ServerName string `yaml:"server-name" json:"serverName"`
+
+	// InsecureSkipVerify disables the verification of the collector's certificate, for testing only
+	InsecureSkipVerify bool `yaml:"insecure-skip-verify" json:"insecureSkipVerify"`
+}
+
 // LogConfig is the main configuration struct for Mango logging
 type LogConfig struct {
 	// MangoConfig is the mango configuration node
@@ -45,10 +123,86 @@ type LogConfig struct {
 
 type MangoConfig struct {
 	// Strict Will enforce the REQUIRED_FIELDS to be present in each log context
+	// The required fields and allowed types are fixed when the logger is created
 	Strict bool `yaml:"strict" json:"strict"`
 
+	// AllowedTypes are the values accepted for TYPE in strict mode - Defaults to ALLOWED_TYPES
+	AllowedTypes []string `yaml:"allowed-types" json:"allowedTypes"`
+
+	// ContextFields are extra context fields (e.g. tenant, environment, userId) emitted as top-level fields of each log
+	// Set them with WithContextField or LogFields.Fields
+	ContextFields []ContextFieldConfig `yaml:"context-fields" json:"contextFields"`
+
 	// CorrelationId configuration
 	CorrelationId *CorrelationIdConfig `yaml:"correlation-id" json:"correlationId"`
+
+	// AddSource adds the file, line and function of the logging call to each log
+	AddSource bool `yaml:"add-source" json:"addSource"`
+
+	// ErrorStack adds the stack of the logging call to the ERROR (and above) logs
+	ErrorStack bool `yaml:"error-stack" json:"errorStack"`
+
+	// DuplicateKeys is the policy applied to attributes sharing a key - Defaults to DuplicateLastWins
+	DuplicateKeys DuplicateKeyPolicy `yaml:"duplicate-keys" json:"duplicateKeys"`
+
+	// Redaction masks sensitive attributes and values before the logs reach any output
+	Redaction *RedactionConfig `yaml:"redaction" json:"redaction"`
+
+	// LevelNames names custom slog levels, e.g. {"TRACE": -8, "NOTICE": 2, "FATAL": 12} (one name per level)
+	// The names are used in StructuredLog.Level and can be used in the output Level thresholds
+	LevelNames map[string]int `yaml:"level-names" json:"levelNames"`
+}
+
+// DuplicateKeyPolicy decides which attributes are kept when several share a key at the same level (groups sharing a key are always combined)
+type DuplicateKeyPolicy string
+
+const (
+	// DuplicateLastWins keeps the last value, at the position of the first attribute (default)
+	DuplicateLastWins DuplicateKeyPolicy = "last-wins"
+
+	// DuplicateFirstWins keeps the first value
+	DuplicateFirstWins DuplicateKeyPolicy = "first-wins"
+
+	// DuplicateKeepBoth keeps every value, renaming the later keys with a suffix (key#2, key#3...)
+	DuplicateKeepBoth DuplicateKeyPolicy = "keep-both"
+)
+
+// ContextFieldConfig defines a custom context field
+type ContextFieldConfig struct {
+	// Name of the field, both in the context (WithContextField) and in the log output
+	// Letters, digits, '.', '_' and '-' only, up to 32 characters, and not one of the StructuredLog fields
+	Name string `yaml:"name" json:"name"`
+
+	// Required fails the logs without the field in strict mode, unless a Default is set
+	Required bool `yaml:"required" json:"required"`
+
+	// AllowedValues restricts the values accepted in strict mode, any value is accepted when empty
+	AllowedValues []string `yaml:"allowed-values" json:"allowedValues"`
+
+	// Default is used when the field is missing from the context, the field being omitted otherwise
+	Default string `yaml:"default" json:"default"`
+}
+
+// RedactionConfig defines the masking of sensitive data in the attributes, message and context fields
+// Secret values are always masked, whether redaction is enabled or not
+type RedactionConfig struct {
+	// Enabled switches on redaction
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Keys are regular expressions matched case insensitively against the attribute keys (at any group depth), masking the whole value
+	// Defaults to DefaultRedactedKeys
+	Keys []string `yaml:"keys" json:"keys"`
+
+	// Patterns mask the matching parts of string values: built-in patterns (RedactPAN, RedactEmail, RedactJWT, RedactBearer)
+	// or regular expressions, only the first capture group being masked when there is one - Defaults to all the built-in patterns
+	Patterns []string `yaml:"patterns" json:"patterns"`
+
+	// Strategy used to mask the values - Defaults to MaskFull
+	Strategy MaskStrategy `yaml:"strategy" json:"strategy"`
+
+	// HashKey is the secret key of the MaskHash digests, required by that strategy
+	// Load it from a secret store (e.g. the environment) rather than a committed file
+	HashKey string `yaml:"hash-key" json:"hashKey"`
 }
 
 // OutConfig provides a structure for defining the configuration of all the logging output
@@ -64,6 +218,15 @@ type OutConfig struct {
 
 	// Syslog configuration node for Syslog output options
 	Syslog *SyslogConfig `yaml:"syslog" json:"syslog"`
+
+	// Async configuration node for handing records over to a background worker
+	Async *AsyncConfig `yaml:"async" json:"async"`
+
+	// Sampling configuration node for sampling and rate limiting high volume records
+	Sampling *SamplingConfig `yaml:"sampling" json:"sampling"`
+
+	// FlightRecorder configuration node for keeping the unwritten records of a correlation id until an error happens
+	FlightRecorder *FlightRecorderConfig `yaml:"flight-recorder" json:"flightRecorder"`
 }
 
 // CorrelationIdConfig defines the configuration of correlationId across mangologger
@@ -74,6 +237,10 @@ type CorrelationIdConfig struct {
 	// AutoGenerate will generate a correlationId if missing from context
 	// This will NOT be generated BEFORE REQUIRED_FIELDS restriction, therefore if correlationId is missing in a strict setup, it will fail regardless of auto-generate flag
 	AutoGenerate bool `yaml:"auto-generate" json:"autoGenerate"`
+
+	// FromTraceId uses the trace-id of the context (OpenTelemetry span or TRACEPARENT) as correlationId when missing from context
+	// It satisfies Strict and takes precedence over AutoGenerate
+	FromTraceId bool `yaml:"from-trace-id" json:"fromTraceId"`
 }
 
 type FileOutputConfig struct {
@@ -83,6 +250,10 @@ type FileOutputConfig struct {
 	// Debug allows debug printout to file
 	Debug bool `yaml:"debug" json:"debug"`
 
+	// Level is the minimum level written to file, overriding Debug when set
+	// Either a slog level (INFO, debug-4, 12) or a name from MangoConfig.LevelNames
+	Level string `yaml:"level" json:"level"`
+
 	// Path is the log file name - It uses <processname>-lumberjack.log in os.TempDir() if empty.
 	Path string `yaml:"path" json:"path"`
 
@@ -97,6 +268,12 @@ type FileOutputConfig struct {
 
 	// Compress old log files - The default is not to perform compression
 	Compress bool `yaml:"compress" json:"compress"`
+
+	// Encoding of the file entries - Defaults to EncodingMangoJSON
+	Encoding Encoding `yaml:"encoding" json:"encoding"`
+
+	// FieldNames renames the StructuredLog fields in the file, keyed by their mango json name (e.g. correlationid: trace.id)
+	FieldNames map[string]string `yaml:"field-names" json:"fieldNames"`
 }
 
 type CliConfig struct {
@@ -108,13 +285,115 @@ type CliConfig struct {
 	Friendly bool `yaml:"friendly" json:"friendly"`
 
 	// FriendlyFormat of the output in normal scenarios and if Friendly enabled
-	// Defaults to DefaultFriendlyFormat applied to all log statements info+
+	// When empty the built-in ConsoleFormatter is applied to all log statements info+, DefaultFriendlyFormat being the former jq template
 	FriendlyFormat string `yaml:"friendly-format" json:"friendlyFormat"`
 
+	// Color of the built-in friendly console output - ColorAuto (default), ColorAlways or ColorNever
+	// ColorAuto colors only when writing to a terminal and the NO_COLOR environment variable is not set
+	Color ColorMode `yaml:"color" json:"color"`
+
 	// Verbose Enable debug to come out to std out following the VerboseFormat
 	Verbose bool `yaml:"verbose" json:"verbose"`
 
-	// VerboseFormat of the DEBUG statements output in verbose mode
+	// Level is the minimum level printed, overriding Verbose when set
+	// Either a slog level (INFO, debug-4, 12) or a name from MangoConfig.LevelNames
+	Level string `yaml:"level" json:"level"`
+
+	// VerboseFormat of the DEBUG (and below) statements output in verbose mode
 	// Defaults to print the whole json object of logger.StructuredLog (using DefaultVerboseFormat)
 	VerboseFormat string `yaml:"verbose-format" json:"verboseFormat"`
+
+	// Encoding of the non friendly output - Defaults to EncodingMangoJSON
+	Encoding Encoding `yaml:"encoding" json:"encoding"`
+
+	// FieldNames renames the StructuredLog fields in the non friendly output, keyed by their mango json name (e.g. correlationid: trace.id)
+	FieldNames map[string]string `yaml:"field-names" json:"fieldNames"`
+}
+
+// OverflowPolicy decides what happens to a record when the async queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for room in the queue (default)
+	OverflowBlock OverflowPolicy = "block"
+
+	// OverflowDropNewest discards the record being logged
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+
+	// OverflowDropOldest discards the oldest queued record to make room
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+
+	// OverflowDropBelowLevel discards records below AsyncConfig.OverflowLevel and blocks for the rest
+	OverflowDropBelowLevel OverflowPolicy = "drop-below-level"
+)
+
+// DefaultAsyncQueueSize is the number of records the async queue holds when AsyncConfig.QueueSize is not set
+const DefaultAsyncQueueSize = 1024
+
+type AsyncConfig struct {
+	// Enabled hands records over to a background worker instead of writing them in the logging goroutine
+	// Required context fields are still validated synchronously
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// QueueSize is the maximum number of records waiting to be written - Defaults to DefaultAsyncQueueSize
+	QueueSize int `yaml:"queue-size" json:"queueSize"`
+
+	// Overflow is the policy applied when the queue is full - Defaults to OverflowBlock
+	Overflow OverflowPolicy `yaml:"overflow" json:"overflow"`
+
+	// OverflowLevel is the level (name) below which records are dropped when using OverflowDropBelowLevel - Defaults to INFO
+	OverflowLevel string `yaml:"overflow-level" json:"overflowLevel"`
+}
+
+// Defaults of SamplingConfig
+const (
+	DefaultSamplingInterval        = "1s"
+	DefaultSamplingSummaryInterval = "1m"
+)
+
+type SamplingConfig struct {
+	// Enabled turns sampling and rate limiting on, records at ERROR and above are never suppressed unless IncludeErrors is set
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Interval is the sampling window (Go duration) - Defaults to DefaultSamplingInterval
+	Interval string `yaml:"interval" json:"interval"`
+
+	// First records of each message and level are logged per interval, then every Thereafter-th one
+	// Sampling is off when both are 0, Thereafter 0 drops every record after the first ones
+	First      int `yaml:"first" json:"first"`
+	Thereafter int `yaml:"thereafter" json:"thereafter"`
+
+	// RateLimits caps the records per second of a level (custom level names allowed), with bursts of the same size, e.g. DEBUG: 100
+	RateLimits map[string]int `yaml:"rate-limits" json:"rateLimits"`
+
+	// SummaryInterval is how often (Go duration) a WARN record reports the suppressed records - Defaults to DefaultSamplingSummaryInterval
+	SummaryInterval string `yaml:"summary-interval" json:"summaryInterval"`
+
+	// IncludeErrors also samples and rate limits the records at ERROR and above
+	IncludeErrors bool `yaml:"include-errors" json:"includeErrors"`
+}
+
+// Defaults of FlightRecorderConfig
+const (
+	DefaultFlightRecorderSize            = 100
+	DefaultFlightRecorderMaxCorrelations = 1000
+)
+
+type FlightRecorderConfig struct {
+	// Enabled keeps the records no output writes (e.g. DEBUG with out.file.debug off, or suppressed by sampling) in memory per correlation id,
+	// writing them to the outputs ahead of the first record at TriggerLevel with the same correlation id
+	// Only the records with a correlation id in context (or a trace id with mango.correlation-id.from-trace-id) are kept
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// Level is the lowest level kept - Defaults to DEBUG
+	Level string `yaml:"level" json:"level"`
+
+	// TriggerLevel is the level flushing the records kept - Defaults to ERROR
+	TriggerLevel string `yaml:"trigger-level" json:"triggerLevel"`
+
+	// Size is the number of records kept per correlation id, the oldest being overwritten - Defaults to DefaultFlightRecorderSize
+	Size int `yaml:"size" json:"size"`
+
+	// MaxCorrelations is the number of correlation ids kept, the least recently used being evicted - Defaults to DefaultFlightRecorderMaxCorrelations
+	MaxCorrelations int `yaml:"max-correlations" json:"maxCorrelations"`
 }
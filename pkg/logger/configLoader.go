@@ -0,0 +1,561 @@
+package logger
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables overlaid by LoadConfig
+const DefaultEnvPrefix = "MANGO"
+
+// ErrInvalidConfig is matched (errors.Is) by every ConfigError
+var ErrInvalidConfig = errors.New("invalid mango logger configuration")
+
+// ConfigError describes a single invalid LogConfig setting
+type ConfigError struct {
+	// Field is the yaml path (e.g. out.file.max-size) or the environment variable of the setting
+	Field string
+
+	// Reason the setting is invalid
+	Reason string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// Is makes every ConfigError match ErrInvalidConfig
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// LoadConfig reads a YAML (.yaml, .yml) or JSON (.json) LogConfig file,
+// overlays the environment variables prefixed with DefaultEnvPrefix, applies the defaults and validates the result.
+// All the problems found are returned together, joined.
+func LoadConfig(path string) (*LogConfig, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log config: %w", err)
+	}
+
+	// unknown (e.g. misspelled) settings are rejected rather than silently leaving the defaults in place
+	config := &LogConfig{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(config); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(config)
+	default:
+		return nil, fmt.Errorf("unsupported log config file extension %q, expected .yaml, .yml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, parseError(path, err)
+	}
+
+	return loadEnv(config, DefaultEnvPrefix)
+}
+
+var (
+	yamlUnknownField = regexp.MustCompile(`^line (\d+): field (.+) not found in type `)
+	jsonUnknownField = regexp.MustCompile(`^json: unknown field "(.+)"$`)
+)
+
+// parseError turns the unknown settings reported by the decoders into ConfigErrors
+func parseError(path string, err error) error {
+	if match := jsonUnknownField.FindStringSubmatch(err.Error()); match != nil {
+		return &ConfigError{Field: match[1], Reason: "unknown setting"}
+	}
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return fmt.Errorf("failed to parse log config %s: %w", path, err)
+	}
+	var errs []error
+	for _, message := range typeErr.Errors {
+		if match := yamlUnknownField.FindStringSubmatch(message); match != nil {
+			errs = append(errs, &ConfigError{Field: match[2], Reason: "unknown setting at line " + match[1]})
+		} else {
+			errs = append(errs, fmt.Errorf("failed to parse log config %s: %s", path, message))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadConfigFromEnv builds a LogConfig from the environment variables with the given prefix only,
+// applies the defaults and validates the result.
+//
+// The variable names follow the yaml keys, upper cased with '-' replaced by '_', e.g. with prefix MANGO:
+//
+//	MANGO_OUT_ENABLED=true
+//	MANGO_OUT_FILE_PATH=/var/log/service.log
+//	MANGO_OUT_SYSLOG_FACILITY=local0
+//	MANGO_MANGO_CORRELATION_ID_AUTO_GENERATE=true
+//	MANGO_MANGO_LEVEL_NAMES=TRACE=-8,FATAL=12
+func LoadConfigFromEnv(prefix string) (*LogConfig, error) {
+	return loadEnv(&LogConfig{}, prefix)
+}
+
+func loadEnv(config *LogConfig, prefix string) (*LogConfig, error) {
+	var errs []error
+	overlayEnv(reflect.ValueOf(config).Elem(), strings.TrimSuffix(prefix, "_"), &errs)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	config.SetDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// overlayEnv sets the fields of the struct from the matching environment variables
+// Nil sub-configs are only allocated when at least one variable targets them
+func overlayEnv(v reflect.Value, prefix string, errs *[]error) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		key := prefix + "_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Pointer && fv.Type().Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				if !envHasPrefix(key + "_") {
+					continue
+				}
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			overlayEnv(fv.Elem(), key, errs)
+			continue
+		}
+
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if err := setFromString(fv, value); err != nil {
+			*errs = append(*errs, &ConfigError{Field: key, Reason: err.Error()})
+		}
+	}
+}
+
+func envHasPrefix(prefix string) bool {
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func setFromString(fv reflect.Value, value string) error {
+	if unmarshaler, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		fv.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		fv.SetInt(int64(n))
+	case reflect.Slice:
+		// []string as comma separated values
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported setting type %s", fv.Type())
+		}
+		items := strings.Split(value, ",")
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			slice.Index(i).SetString(strings.TrimSpace(item))
+		}
+		fv.Set(slice)
+	case reflect.Map:
+		// map[string]int and map[string]string as comma separated key=value pairs
+		m := reflect.MakeMap(fv.Type())
+		for _, pair := range strings.Split(value, ",") {
+			k, v, found := strings.Cut(pair, "=")
+			if !found {
+				return fmt.Errorf("%q is not a list of name=value pairs", value)
+			}
+			item := reflect.New(fv.Type().Elem()).Elem()
+			if err := setFromString(item, strings.TrimSpace(v)); err != nil {
+				return fmt.Errorf("%q is not a list of name=value pairs: %w", value, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)), item)
+		}
+		fv.Set(m)
+	default:
+		return fmt.Errorf("unsupported setting type %s", fv.Type())
+	}
+	return nil
+}
+
+// SetDefaults fills the nil sub-configs with disabled ones and the unset settings with their defaults
+func (c *LogConfig) SetDefaults() {
+	if c.MangoConfig == nil {
+		c.MangoConfig = &MangoConfig{}
+	}
+	if c.MangoConfig.CorrelationId == nil {
+		c.MangoConfig.CorrelationId = &CorrelationIdConfig{}
+	}
+	if c.MangoConfig.DuplicateKeys == "" {
+		c.MangoConfig.DuplicateKeys = DuplicateLastWins
+	}
+	if redaction := c.MangoConfig.Redaction; redaction != nil {
+		if redaction.Keys == nil {
+			redaction.Keys = slices.Clone(DefaultRedactedKeys)
+		}
+		if redaction.Patterns == nil {
+			redaction.Patterns = slices.Clone(redactionPatternNames)
+		}
+		if redaction.Strategy == "" {
+			redaction.Strategy = MaskFull
+		}
+	}
+	if c.Out == nil {
+		c.Out = &OutConfig{}
+	}
+	if c.Out.File == nil {
+		c.Out.File = &FileOutputConfig{}
+	}
+	if c.Out.Cli == nil {
+		c.Out.Cli = &CliConfig{}
+	}
+	if c.Out.Cli.VerboseFormat == "" {
+		c.Out.Cli.VerboseFormat = DefaultVerboseFormat
+	}
+	if c.Out.Cli.Color == "" {
+		c.Out.Cli.Color = ColorAuto
+	}
+	if c.Out.Cli.Encoding == "" {
+		c.Out.Cli.Encoding = EncodingMangoJSON
+	}
+	if c.Out.File.Encoding == "" {
+		c.Out.File.Encoding = EncodingMangoJSON
+	}
+	if c.Out.Syslog == nil {
+		c.Out.Syslog = &SyslogConfig{}
+	}
+	if c.Out.Syslog.Encoding == "" {
+		c.Out.Syslog.Encoding = EncodingMangoJSON
+	}
+	if c.Out.Syslog.Format == "" {
+		c.Out.Syslog.Format = SyslogFormatRFC3164
+	}
+	if c.Out.Syslog.Framing == "" {
+		c.Out.Syslog.Framing = SyslogFramingOctetCounting
+	}
+	if c.Out.Syslog.StructuredDataID == "" {
+		c.Out.Syslog.StructuredDataID = DefaultSyslogStructuredDataID
+	}
+	if c.Out.Async != nil {
+		if c.Out.Async.QueueSize == 0 {
+			c.Out.Async.QueueSize = DefaultAsyncQueueSize
+		}
+		if c.Out.Async.Overflow == "" {
+			c.Out.Async.Overflow = OverflowBlock
+		}
+	}
+	if c.Out.Sampling != nil {
+		if c.Out.Sampling.Interval == "" {
+			c.Out.Sampling.Interval = DefaultSamplingInterval
+		}
+		if c.Out.Sampling.SummaryInterval == "" {
+			c.Out.Sampling.SummaryInterval = DefaultSamplingSummaryInterval
+		}
+	}
+	if c.Out.FlightRecorder != nil {
+		if c.Out.FlightRecorder.Size == 0 {
+			c.Out.FlightRecorder.Size = DefaultFlightRecorderSize
+		}
+		if c.Out.FlightRecorder.MaxCorrelations == 0 {
+			c.Out.FlightRecorder.MaxCorrelations = DefaultFlightRecorderMaxCorrelations
+		}
+	}
+}
+
+// clone deep copies the configuration, so applying defaults never changes the sub-configs of the caller
+func (c *LogConfig) clone() *LogConfig {
+	clone := *c
+	if mango := clonePtr(c.MangoConfig); mango != nil {
+		mango.AllowedTypes = slices.Clone(mango.AllowedTypes)
+		mango.ContextFields = slices.Clone(mango.ContextFields)
+		for i := range mango.ContextFields {
+			mango.ContextFields[i].AllowedValues = slices.Clone(mango.ContextFields[i].AllowedValues)
+		}
+		mango.CorrelationId = clonePtr(mango.CorrelationId)
+		if redaction := clonePtr(mango.Redaction); redaction != nil {
+			redaction.Keys = slices.Clone(redaction.Keys)
+			redaction.Patterns = slices.Clone(redaction.Patterns)
+			mango.Redaction = redaction
+		}
+		mango.LevelNames = maps.Clone(mango.LevelNames)
+		clone.MangoConfig = mango
+	}
+	if out := clonePtr(c.Out); out != nil {
+		if file := clonePtr(out.File); file != nil {
+			file.FieldNames = maps.Clone(file.FieldNames)
+			out.File = file
+		}
+		if cli := clonePtr(out.Cli); cli != nil {
+			cli.FieldNames = maps.Clone(cli.FieldNames)
+			out.Cli = cli
+		}
+		if syslog := clonePtr(out.Syslog); syslog != nil {
+			syslog.TLS = clonePtr(syslog.TLS)
+			syslog.FieldNames = maps.Clone(syslog.FieldNames)
+			out.Syslog = syslog
+		}
+		out.Async = clonePtr(out.Async)
+		if sampling := clonePtr(out.Sampling); sampling != nil {
+			sampling.RateLimits = maps.Clone(sampling.RateLimits)
+			out.Sampling = sampling
+		}
+		out.FlightRecorder = clonePtr(out.FlightRecorder)
+		clone.Out = out
+	}
+	return &clone
+}
+
+// clonePtr is a shallow copy of *p, nil when p is nil
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
+
+// contextFieldName is the syntax of ContextFieldConfig.Name, usable as json key, logfmt key and RFC 5424 SD-PARAM name
+var contextFieldName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// Validate checks the whole configuration, returning every problem found joined together as ConfigError
+// Nil sub-configs are valid, SetDefaults fills them with disabled defaults
+func (c *LogConfig) Validate() error {
+	var errs []error
+	invalid := func(field, reason string, args ...any) {
+		errs = append(errs, &ConfigError{Field: field, Reason: fmt.Sprintf(reason, args...)})
+	}
+	var customFields []string
+
+	if c.MangoConfig != nil {
+		switch c.MangoConfig.DuplicateKeys {
+		case "", DuplicateLastWins, DuplicateFirstWins, DuplicateKeepBoth:
+		default:
+			invalid("mango.duplicate-keys", "unknown policy %q, expected %s, %s or %s", c.MangoConfig.DuplicateKeys, DuplicateLastWins, DuplicateFirstWins, DuplicateKeepBoth)
+		}
+		if slices.Contains(c.MangoConfig.AllowedTypes, "") {
+			invalid("mango.allowed-types", "must not contain an empty type")
+		}
+		var names []string
+		for i, field := range c.MangoConfig.ContextFields {
+			name := fmt.Sprintf("mango.context-fields[%d]", i)
+			switch {
+			case !contextFieldName.MatchString(field.Name):
+				invalid(name+".name", "%q must be 1 to 32 letters, digits, '.', '_' or '-'", field.Name)
+			case slices.Contains(standardFieldNames, field.Name) || ctxKey(field.Name) == TRACEPARENT:
+				invalid(name+".name", "%q is a reserved field", field.Name)
+			case slices.Contains(names, field.Name):
+				invalid(name+".name", "duplicate field %q", field.Name)
+			}
+			names = append(names, field.Name)
+			if field.Default != "" && len(field.AllowedValues) > 0 && !slices.Contains(field.AllowedValues, field.Default) {
+				invalid(name+".default", "%q is not one of the allowed values %q", field.Default, field.AllowedValues)
+			}
+		}
+		customFields = names
+		if redaction := c.MangoConfig.Redaction; redaction != nil {
+			for i, key := range redaction.Keys {
+				if _, err := compileKeyPattern(key); err != nil {
+					invalid(fmt.Sprintf("mango.redaction.keys[%d]", i), "%s", err.Error())
+				}
+			}
+			for i, pattern := range redaction.Patterns {
+				if _, err := compileValuePattern(pattern); err != nil {
+					invalid(fmt.Sprintf("mango.redaction.patterns[%d]", i), "%s", err.Error())
+				}
+			}
+			switch redaction.Strategy {
+			case "", MaskFull, MaskPartial, MaskHash:
+			default:
+				invalid("mango.redaction.strategy", "unknown strategy %q, expected %s, %s or %s", redaction.Strategy, MaskFull, MaskPartial, MaskHash)
+			}
+			if redaction.Strategy == MaskHash && redaction.HashKey == "" {
+				invalid("mango.redaction.hash-key", "required by the %s strategy", MaskHash)
+			}
+		}
+	}
+	if c.Out == nil {
+		return errors.Join(errs...)
+	}
+
+	var levels levelNames
+	if c.MangoConfig != nil {
+		levels = newLevelNames(c.MangoConfig.LevelNames)
+	}
+	validLevel := func(field, level string) {
+		if level == "" {
+			return
+		}
+		if _, err := levels.parse(level); err != nil {
+			invalid(field, "%s", err.Error())
+		}
+	}
+	validEncoding := func(output string, encoding Encoding, fieldNames map[string]string) {
+		if _, err := newEncoder(encoding, fieldNames, customFields); err != nil {
+			invalid(output+".encoding", "%s", err.Error())
+		}
+	}
+
+	if file := c.Out.File; file != nil {
+		validLevel("out.file.level", file.Level)
+		validEncoding("out.file", file.Encoding, file.FieldNames)
+		for _, setting := range []struct {
+			field string
+			value int
+		}{{"max-size", file.MaxSize}, {"max-backups", file.MaxBackups}, {"max-age", file.MaxAge}} {
+			if setting.value < 0 {
+				invalid("out.file."+setting.field, "must not be negative, got %d", setting.value)
+			}
+		}
+	}
+
+	if cli := c.Out.Cli; cli != nil {
+		validLevel("out.cli.level", cli.Level)
+		validEncoding("out.cli", cli.Encoding, cli.FieldNames)
+		switch cli.Color {
+		case "", ColorAuto, ColorAlways, ColorNever:
+		default:
+			invalid("out.cli.color", "unknown color mode %q", cli.Color)
+		}
+		for _, setting := range []struct {
+			field  string
+			format string
+		}{{"friendly-format", cli.FriendlyFormat}, {"verbose-format", cli.VerboseFormat}} {
+			if setting.format == "" {
+				continue
+			}
+			if _, err := compileGoJQ(setting.format); err != nil {
+				invalid("out.cli."+setting.field, "invalid jq format: %s", err.Error())
+			}
+		}
+	}
+
+	if syslogConfig := c.Out.Syslog; syslogConfig != nil {
+		validLevel("out.syslog.level", syslogConfig.Level)
+		validEncoding("out.syslog", syslogConfig.Encoding, syslogConfig.FieldNames)
+		if err := checkSyslogTransport(syslogConfig); syslogConfig.Facility != "" && err != nil {
+			invalid("out.syslog.network", "%s", err.Error())
+		}
+		if _, ok := syslogFacilityCodes[syslogConfig.Facility]; syslogConfig.Facility != "" && !ok {
+			invalid("out.syslog.facility", "unknown facility %q", syslogConfig.Facility)
+		}
+		switch syslogConfig.Network {
+		case "", "unix", "unixgram":
+		default:
+			if !isNetworkSyslog(syslogConfig.Network) {
+				invalid("out.syslog.network", "unknown network %q, expected udp, tcp, tls, unix or unixgram", syslogConfig.Network)
+			} else if syslogConfig.Address == "" {
+				invalid("out.syslog.address", "required with network %s", syslogConfig.Network)
+			}
+		}
+		switch syslogConfig.Format {
+		case "", SyslogFormatRFC3164, SyslogFormatRFC5424:
+		default:
+			invalid("out.syslog.format", "unknown format %q, expected %s or %s", syslogConfig.Format, SyslogFormatRFC3164, SyslogFormatRFC5424)
+		}
+		switch syslogConfig.Framing {
+		case "", SyslogFramingOctetCounting, SyslogFramingNonTransparent:
+		default:
+			invalid("out.syslog.framing", "unknown framing %q, expected %s or %s", syslogConfig.Framing, SyslogFramingOctetCounting, SyslogFramingNonTransparent)
+		}
+	}
+
+	if async := c.Out.Async; async != nil {
+		if async.QueueSize < 0 {
+			invalid("out.async.queue-size", "must not be negative, got %d", async.QueueSize)
+		}
+		validLevel("out.async.overflow-level", async.OverflowLevel)
+		switch async.Overflow {
+		case "", OverflowBlock, OverflowDropNewest, OverflowDropOldest, OverflowDropBelowLevel:
+		default:
+			invalid("out.async.overflow", "unknown overflow policy %q", async.Overflow)
+		}
+	}
+
+	if sampling := c.Out.Sampling; sampling != nil {
+		validDuration := func(field, value string) {
+			if value == "" {
+				return
+			}
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				invalid(field, "invalid duration %q, expected a positive Go duration such as 1s or 500ms", value)
+			}
+		}
+		validDuration("out.sampling.interval", sampling.Interval)
+		validDuration("out.sampling.summary-interval", sampling.SummaryInterval)
+		if sampling.First < 0 {
+			invalid("out.sampling.first", "must not be negative, got %d", sampling.First)
+		}
+		if sampling.Thereafter < 0 {
+			invalid("out.sampling.thereafter", "must not be negative, got %d", sampling.Thereafter)
+		}
+		for _, level := range slices.Sorted(maps.Keys(sampling.RateLimits)) {
+			rate := sampling.RateLimits[level]
+			validLevel("out.sampling.rate-limits", level)
+			if rate <= 0 {
+				invalid("out.sampling.rate-limits", "rate of %s must be positive, got %d", level, rate)
+			}
+		}
+	}
+
+	if recorder := c.Out.FlightRecorder; recorder != nil {
+		validLevel("out.flight-recorder.level", recorder.Level)
+		validLevel("out.flight-recorder.trigger-level", recorder.TriggerLevel)
+		level, levelErr := levels.newLevelVar(recorder.Level, slog.LevelDebug)
+		trigger, triggerErr := levels.newLevelVar(recorder.TriggerLevel, slog.LevelError)
+		if levelErr == nil && triggerErr == nil && level.Level() >= trigger.Level() {
+			invalid("out.flight-recorder.level", "must be below the trigger level %s, got %s", levels.name(trigger.Level()), levels.name(level.Level()))
+		}
+		if recorder.Size < 0 {
+			invalid("out.flight-recorder.size", "must not be negative, got %d", recorder.Size)
+		}
+		if recorder.MaxCorrelations < 0 {
+			invalid("out.flight-recorder.max-correlations", "must not be negative, got %d", recorder.MaxCorrelations)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,299 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testYamlConfig = `
+mango:
+  strict: true
+  correlation-id:
+    strict: true
+    auto-generate: true
+  level-names:
+    TRACE: -8
+out:
+  enabled: true
+  cli:
+    enabled: true
+    friendly: true
+    level: TRACE
+  file:
+    enabled: true
+    path: /var/log/mango.log
+    max-size: 10
+  syslog:
+    facility: local0
+    network: tcp
+    address: collector:601
+    format: rfc5424
+  async:
+    enabled: true
+    overflow: drop-below-level
+    overflow-level: WARN
+`
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfig_Yaml(t *testing.T) {
+	config, err := LoadConfig(writeConfigFile(t, "log.yaml", testYamlConfig))
+	assert.NoError(t, err)
+
+	assert.True(t, config.MangoConfig.Strict)
+	assert.True(t, config.MangoConfig.CorrelationId.AutoGenerate)
+	assert.Equal(t, map[string]int{"TRACE": -8}, config.MangoConfig.LevelNames)
+	assert.Equal(t, "TRACE", config.Out.Cli.Level)
+	assert.Empty(t, config.Out.Cli.FriendlyFormat)
+	assert.Equal(t, ColorAuto, config.Out.Cli.Color)
+	assert.Equal(t, DefaultVerboseFormat, config.Out.Cli.VerboseFormat)
+	assert.Equal(t, "/var/log/mango.log", config.Out.File.Path)
+	assert.Equal(t, 10, config.Out.File.MaxSize)
+	assert.Equal(t, SyslogFormatRFC5424, config.Out.Syslog.Format)
+	assert.Equal(t, SyslogFramingOctetCounting, config.Out.Syslog.Framing)
+	assert.Equal(t, DefaultSyslogStructuredDataID, config.Out.Syslog.StructuredDataID)
+	assert.Equal(t, OverflowDropBelowLevel, config.Out.Async.Overflow)
+	assert.Equal(t, "WARN", config.Out.Async.OverflowLevel)
+	assert.Equal(t, DefaultAsyncQueueSize, config.Out.Async.QueueSize)
+}
+
+func TestLoadConfig_JsonWithDefaults(t *testing.T) {
+	config, err := LoadConfig(writeConfigFile(t, "log.json", `{"out": {"enabled": true, "file": {"enabled": true, "path": "/tmp/x.log"}}}`))
+	assert.NoError(t, err)
+
+	assert.True(t, config.Out.File.Enabled)
+	assert.NotNil(t, config.MangoConfig.CorrelationId)
+	assert.False(t, config.Out.Cli.Enabled)
+	assert.Empty(t, config.Out.Syslog.Facility)
+	assert.Nil(t, config.Out.Async)
+}
+
+func TestLoadConfig_EnvOverlay(t *testing.T) {
+	t.Setenv("MANGO_OUT_FILE_PATH", "/from/env.log")
+	t.Setenv("MANGO_OUT_CLI_ENABLED", "false")
+	t.Setenv("MANGO_MANGO_CORRELATION_ID_AUTO_GENERATE", "false")
+
+	config, err := LoadConfig(writeConfigFile(t, "log.yml", testYamlConfig))
+	assert.NoError(t, err)
+	assert.Equal(t, "/from/env.log", config.Out.File.Path)
+	assert.False(t, config.Out.Cli.Enabled)
+	assert.False(t, config.MangoConfig.CorrelationId.AutoGenerate)
+	assert.True(t, config.MangoConfig.CorrelationId.Strict)
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("APP_OUT_ENABLED", "true")
+	t.Setenv("APP_OUT_SYSLOG_FACILITY", "local3")
+	t.Setenv("APP_OUT_FILE_MAX_SIZE", "5")
+	t.Setenv("APP_OUT_ASYNC_ENABLED", "1")
+	t.Setenv("APP_OUT_ASYNC_OVERFLOW_LEVEL", "ERROR")
+	t.Setenv("APP_MANGO_LEVEL_NAMES", "TRACE=-8, FATAL=12")
+	t.Setenv("APP_MANGO_ALLOWED_TYPES", "Business, Audit")
+	t.Setenv("APP_OUT_FILE_ENCODING", "ecs")
+	t.Setenv("APP_OUT_FILE_FIELD_NAMES", "correlationid=trace.id,logId=event.id")
+
+	config, err := LoadConfigFromEnv("APP_")
+	assert.NoError(t, err)
+	assert.True(t, config.Out.Enabled)
+	assert.Equal(t, SyslogFacility(SyslogFacilityLocal3), config.Out.Syslog.Facility)
+	assert.Equal(t, 5, config.Out.File.MaxSize)
+	assert.True(t, config.Out.Async.Enabled)
+	assert.Equal(t, "ERROR", config.Out.Async.OverflowLevel)
+	assert.Equal(t, map[string]int{"TRACE": -8, "FATAL": 12}, config.MangoConfig.LevelNames)
+	assert.Equal(t, []string{"Business", "Audit"}, config.MangoConfig.AllowedTypes)
+	assert.Equal(t, EncodingECS, config.Out.File.Encoding)
+	assert.Equal(t, map[string]string{"correlationid": "trace.id", "logId": "event.id"}, config.Out.File.FieldNames)
+	assert.Equal(t, EncodingMangoJSON, config.Out.Syslog.Encoding)
+}
+
+func TestLoadConfigFromEnv_InvalidValues(t *testing.T) {
+	t.Setenv("APP_OUT_ENABLED", "yes please")
+	t.Setenv("APP_OUT_FILE_MAX_SIZE", "big")
+	t.Setenv("APP_MANGO_LEVEL_NAMES", "TRACE")
+
+	_, err := LoadConfigFromEnv("APP")
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, "APP_OUT_ENABLED")
+	assert.ErrorContains(t, err, "APP_OUT_FILE_MAX_SIZE")
+	assert.ErrorContains(t, err, "APP_MANGO_LEVEL_NAMES")
+}
+
+func TestLoadConfig_AggregatedValidation(t *testing.T) {
+	_, err := LoadConfig(writeConfigFile(t, "log.yaml", `
+out:
+  cli:
+    friendly-format: '"\(.level'
+    level: LOUD
+  file:
+    max-size: -1
+    max-age: -2
+    encoding: xml
+  syslog:
+    facility: local9
+    field-names:
+      user: usr
+    network: udp
+    format: rfc1234
+  async:
+    overflow: explode
+`))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr))
+
+	for _, field := range []string{
+		"out.cli.friendly-format", "out.cli.level", "out.file.max-size", "out.file.max-age",
+		"out.file.encoding", "out.syslog.facility", "out.syslog.address", "out.syslog.format",
+		"out.syslog.encoding", "out.async.overflow",
+	} {
+		assert.ErrorContains(t, err, field+":")
+	}
+	assert.Len(t, strings.Split(err.Error(), "\n"), 10)
+}
+
+func TestLogConfig_ValidateContextFields(t *testing.T) {
+	_, err := LoadConfig(writeConfigFile(t, "log.yaml", `
+mango:
+  context-fields:
+    - name: tenant
+      required: true
+    - name: tenant
+    - name: level
+    - name: "user id"
+    - name: environment
+      allowed-values: [dev, prod]
+      default: qa
+out:
+  file:
+    encoding: ecs
+    field-names:
+      tenant: organization.id
+      region: cloud.region
+`))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, `mango.context-fields[1].name: duplicate field "tenant"`)
+	assert.ErrorContains(t, err, "mango.context-fields[2].name:")
+	assert.ErrorContains(t, err, "mango.context-fields[3].name:")
+	assert.ErrorContains(t, err, "mango.context-fields[4].default:")
+	assert.ErrorContains(t, err, `out.file.encoding: unknown field "region"`)
+	assert.NotContains(t, err.Error(), `unknown field "tenant"`)
+	assert.Len(t, strings.Split(err.Error(), "\n"), 5)
+}
+
+func TestLogConfig_ValidateSampling(t *testing.T) {
+	config, err := LoadConfig(writeConfigFile(t, "log.yaml", `
+out:
+  sampling:
+    enabled: true
+    first: 10
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultSamplingInterval, config.Out.Sampling.Interval)
+	assert.Equal(t, DefaultSamplingSummaryInterval, config.Out.Sampling.SummaryInterval)
+
+	_, err = LoadConfig(writeConfigFile(t, "log.yaml", `
+out:
+  sampling:
+    enabled: true
+    interval: 0s
+    summary-interval: often
+    first: -1
+    thereafter: -2
+    rate-limits:
+      DEBUG: 0
+      VERBOSE: 10
+`))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, `out.sampling.interval: invalid duration "0s"`)
+	assert.ErrorContains(t, err, `out.sampling.summary-interval: invalid duration "often"`)
+	assert.ErrorContains(t, err, "out.sampling.first: must not be negative, got -1")
+	assert.ErrorContains(t, err, "out.sampling.thereafter: must not be negative, got -2")
+	assert.ErrorContains(t, err, "out.sampling.rate-limits: rate of DEBUG must be positive, got 0")
+	assert.ErrorContains(t, err, "out.sampling.rate-limits: unknown log level \"VERBOSE\"")
+	assert.Len(t, strings.Split(err.Error(), "\n"), 6)
+}
+
+func TestLogConfig_ValidateFlightRecorder(t *testing.T) {
+	config, err := LoadConfig(writeConfigFile(t, "log.yaml", `
+out:
+  flight-recorder:
+    enabled: true
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultFlightRecorderSize, config.Out.FlightRecorder.Size)
+	assert.Equal(t, DefaultFlightRecorderMaxCorrelations, config.Out.FlightRecorder.MaxCorrelations)
+
+	_, err = LoadConfig(writeConfigFile(t, "log.yaml", `
+out:
+  flight-recorder:
+    enabled: true
+    level: ERROR
+    trigger-level: WARN
+    size: -1
+    max-correlations: -5
+`))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, "out.flight-recorder.level: must be below the trigger level WARN, got ERROR")
+	assert.ErrorContains(t, err, "out.flight-recorder.size: must not be negative, got -1")
+	assert.ErrorContains(t, err, "out.flight-recorder.max-correlations: must not be negative, got -5")
+
+	_, err = LoadConfig(writeConfigFile(t, "log.yaml", `
+out:
+  flight-recorder:
+    enabled: true
+    trigger-level: FATAL
+`))
+	assert.ErrorContains(t, err, `out.flight-recorder.trigger-level: unknown log level "FATAL"`)
+}
+
+func TestLogConfig_ValidateNilSubConfigs(t *testing.T) {
+	// nil sub-configs are filled by SetDefaults, validating before or after the defaults
+	assert.NoError(t, (&LogConfig{}).Validate())
+	assert.NoError(t, (&LogConfig{MangoConfig: &MangoConfig{}, Out: &OutConfig{}}).Validate())
+	err := (&LogConfig{Out: &OutConfig{File: &FileOutputConfig{MaxSize: -1}}}).Validate()
+	assert.ErrorContains(t, err, "out.file.max-size")
+	assert.Len(t, strings.Split(err.Error(), "\n"), 1)
+}
+
+func TestLoadConfig_UnknownSettings(t *testing.T) {
+	_, err := LoadConfig(writeConfigFile(t, "log.yaml", `
+outpt:
+  enabled: true
+out:
+  file:
+    enabled: true
+    max-sise: 10
+`))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.EqualError(t, err, "outpt: unknown setting at line 2\nmax-sise: unknown setting at line 7")
+
+	_, err = LoadConfig(writeConfigFile(t, "log.json", `{"out": {"enabled": true, "cli": {"verbos": true}}}`))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.EqualError(t, err, "verbos: unknown setting")
+
+	// an empty file is the default configuration
+	config, err := LoadConfig(writeConfigFile(t, "log.yaml", ""))
+	assert.NoError(t, err)
+	assert.False(t, config.Out.Enabled)
+}
+
+func TestLoadConfig_FileErrors(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read log config")
+
+	_, err = LoadConfig(writeConfigFile(t, "log.toml", ""))
+	assert.ErrorContains(t, err, "unsupported log config file extension")
+
+	_, err = LoadConfig(writeConfigFile(t, "log.json", "{"))
+	assert.ErrorContains(t, err, "failed to parse log config")
+}
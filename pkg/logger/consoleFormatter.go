@@ -0,0 +1,253 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ColorMode decides when the console output is colored
+type ColorMode string
+
+const (
+	// ColorAuto colors when writing to a terminal and NO_COLOR is not set (default)
+	ColorAuto ColorMode = "auto"
+
+	// ColorAlways colors regardless of the terminal
+	ColorAlways ColorMode = "always"
+
+	// ColorNever never colors
+	ColorNever ColorMode = "never"
+)
+
+// ANSI escape codes used by the console output
+const (
+	ansiReset   = "\033[0m"
+	ansiBold    = "\033[1m"
+	ansiDim     = "\033[2m"
+	ansiRed     = "\033[31m"
+	ansiGreen   = "\033[32m"
+	ansiYellow  = "\033[33m"
+	ansiBlue    = "\033[34m"
+	ansiMagenta = "\033[35m"
+	ansiCyan    = "\033[36m"
+)
+
+// consoleTimeFormat is fixed width to keep the columns aligned
+const consoleTimeFormat = "2006-01-02 15:04:05.000"
+
+// consoleMessageWidth is the width in runes of the operation and message column, the attributes of short messages starting after it
+const consoleMessageWidth = 40
+
+// ConsoleFormatter renders a StructuredLog as a human friendly, optionally colored, line:
+//
+//	2025-01-15 09:53:34.717 INFO  cart-create  cart created                 country=IE  items=3
+//
+// Nested attributes are flattened with dotted keys and multiline values (e.g. stack traces) are printed indented below the line.
+type ConsoleFormatter struct {
+	color bool
+
+	// levelWidth is the width the level names are padded to, the longest standard name (5) by default
+	levelWidth int
+}
+
+// NewConsoleFormatter creates a ConsoleFormatter, coloring with ANSI codes when color is true
+func NewConsoleFormatter(color bool) *ConsoleFormatter {
+	return &ConsoleFormatter{color: color, levelWidth: levelNames{}.width()}
+}
+
+// shouldColor resolves the ColorMode for the file written to
+func shouldColor(mode ColorMode, file *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (f *ConsoleFormatter) Format(log *StructuredLog) ([]byte, error) {
+	var b strings.Builder
+
+	b.WriteString(f.paint(ansiDim, consoleTime(log.Timestamp)))
+	b.WriteByte(' ')
+
+	levelName := log.LevelName
+	if levelName == "" {
+		levelName = log.Level.String()
+	}
+	b.WriteString(f.paint(levelColor(log.Level), fmt.Sprintf("%-*s", f.levelWidth, levelName)))
+	b.WriteByte(' ')
+
+	width := 0
+	if log.Operation != "" {
+		b.WriteString(f.paint(ansiCyan, log.Operation))
+		b.WriteString("  ")
+		width = utf8.RuneCountInString(log.Operation) + 2
+	}
+
+	message := fmt.Sprint(log.Message)
+	b.WriteString(f.paint(ansiBold, message))
+	width += utf8.RuneCountInString(message)
+
+	var multiline []string
+	attrs := flattenAttributes("", log.Attributes)
+	if log.Correlationid != "" {
+		attrs = append(attrs, consoleAttr{key: "correlationid", value: log.Correlationid})
+	}
+	for _, field := range log.fields() {
+		if _, ok := log.Fields[field.name]; ok {
+			attrs = append(attrs, consoleAttr{key: field.name, value: field.value})
+		}
+	}
+	if log.Source != nil {
+		attrs = append(attrs, consoleAttr{key: "source", value: fmt.Sprintf("%s:%d", filepath.Base(log.Source.File), log.Source.Line)})
+	}
+	if log.Stack != "" {
+		attrs = append(attrs, consoleAttr{key: "stack", value: log.Stack})
+	}
+	for i, attr := range attrs {
+		value := consoleValue(attr.value)
+		if strings.Contains(value, "\n") {
+			multiline = append(multiline, attr.key, value)
+			continue
+		}
+		if i == 0 && width < consoleMessageWidth {
+			b.WriteString(strings.Repeat(" ", consoleMessageWidth-width))
+		}
+		b.WriteString("  ")
+		b.WriteString(f.paint(ansiDim, attr.key+"="))
+		b.WriteString(value)
+	}
+
+	for i := 0; i < len(multiline); i += 2 {
+		b.WriteString("\n    ")
+		b.WriteString(f.paint(ansiDim, multiline[i]+":"))
+		for _, line := range strings.Split(strings.TrimRight(multiline[i+1], "\n"), "\n") {
+			b.WriteString("\n      ")
+			b.WriteString(line)
+		}
+	}
+
+	return []byte(b.String()), nil
+}
+
+func (f *ConsoleFormatter) paint(color, s string) string {
+	if !f.color || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// levelColor of the level, custom levels take the color of the closest level below them
+func levelColor(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return ansiDim
+	case level < slog.LevelInfo:
+		return ansiBlue
+	case level < slog.LevelWarn:
+		return ansiGreen
+	case level < slog.LevelError:
+		return ansiYellow
+	case level < slog.LevelError+4:
+		return ansiRed
+	default:
+		return ansiBold + ansiMagenta
+	}
+}
+
+// consoleTime renders the log timestamp in the fixed width consoleTimeFormat
+func consoleTime(timestamp string) string {
+	t, err := time.Parse(RFC3339NanoMC, timestamp)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339Nano, timestamp)
+	}
+	if err != nil {
+		return fmt.Sprintf("%-*s", len(consoleTimeFormat), timestamp)
+	}
+	return t.Format(consoleTimeFormat)
+}
+
+type consoleAttr struct {
+	key   string
+	value any
+}
+
+// flattenAttributes keeps the order of the attributes, nested groups becoming dotted keys
+func flattenAttributes(prefix string, attributes *OrderedMap) []consoleAttr {
+	var attrs []consoleAttr
+	for _, key := range attributes.Keys() {
+		value, _ := attributes.Get(key)
+		attrs = append(attrs, flattenValue(prefix+key, value)...)
+	}
+	return attrs
+}
+
+// flattenValue flattens nested groups and maps with dotted keys, the keys of plain maps being sorted
+func flattenValue(key string, value any) []consoleAttr {
+	switch v := value.(type) {
+	case *OrderedMap:
+		return flattenAttributes(key+".", v)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		var attrs []consoleAttr
+		for _, k := range keys {
+			attrs = append(attrs, flattenValue(key+"."+k, v[k])...)
+		}
+		return attrs
+	}
+	return []consoleAttr{{key: key, value: value}}
+}
+
+// consoleValue renders an attribute value, quoting strings only when needed
+func consoleValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return quoteIfNeeded(v)
+	case error:
+		return quoteIfNeeded(fmt.Sprintf("%+v", v))
+	case fmt.Stringer:
+		return quoteIfNeeded(v.String())
+	case nil:
+		return "<nil>"
+	}
+	if b, err := json.Marshal(value); err == nil {
+		return string(b)
+	}
+	return quoteIfNeeded(fmt.Sprint(value))
+}
+
+// quoteIfNeeded quotes single line strings that are empty or contain spaces, quotes or control characters
+// Multiline strings are kept as is, to be printed below the log line
+func quoteIfNeeded(s string) string {
+	if strings.Contains(s, "\n") {
+		return s
+	}
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r)
+	}) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleFormatter_Format(t *testing.T) {
+	log := &StructuredLog{
+		Timestamp:     "2025-01-15T09:53:34.717+0100",
+		Level:         slog.LevelInfo,
+		LevelName:     "INFO",
+		Operation:     "cart-create",
+		Correlationid: "abc",
+		Message:       "cart created",
+		Attributes: attributesOf(
+			"items", 3,
+			"country", "IE",
+			"note", "two words",
+			"request", map[string]interface{}{"status": 200},
+		),
+	}
+
+	result, err := NewConsoleFormatter(false).Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, `2025-01-15 09:53:34.717 INFO  cart-create  cart created`+strings.Repeat(" ", 15)+
+		`  items=3  country=IE  note="two words"  request.status=200  correlationid=abc`, string(result))
+}
+
+func TestConsoleFormatter_Alignment(t *testing.T) {
+	format := func(operation, message string) string {
+		log := &StructuredLog{Timestamp: "2025-01-15T09:53:34Z", LevelName: "INFO", Operation: operation, Message: message, Attributes: attributesOf("n", 1)}
+		result, err := NewConsoleFormatter(false).Format(log)
+		assert.NoError(t, err)
+		return string(result)
+	}
+
+	// the attributes start at the same column with or without an operation, multibyte characters counting once
+	lines := []string{format("", "cart created"), format("cart-create", "cart created"), format("commande", "panier créé €")}
+	for _, line := range lines[1:] {
+		assert.Equal(t, utf8.RuneCountInString(lines[0][:strings.Index(lines[0], "n=1")]), utf8.RuneCountInString(line[:strings.Index(line, "n=1")]), line)
+	}
+}
+
+func TestConsoleFormatter_CustomLevelAndColor(t *testing.T) {
+	log := &StructuredLog{Timestamp: "2025-01-15T09:53:34Z", Level: slog.Level(12), LevelName: "FATAL", Message: "down"}
+
+	result, err := NewConsoleFormatter(false).Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-15 09:53:34.000 FATAL down", string(result))
+
+	result, err = NewConsoleFormatter(true).Format(log)
+	assert.NoError(t, err)
+	assert.Contains(t, string(result), ansiBold+ansiMagenta+"FATAL"+ansiReset)
+
+	log.Level, log.LevelName = slog.LevelWarn, "WARN"
+	result, _ = NewConsoleFormatter(true).Format(log)
+	assert.Contains(t, string(result), ansiYellow+"WARN "+ansiReset)
+}
+
+func TestConsoleFormatter_MultilineStack(t *testing.T) {
+	log := &StructuredLog{
+		Timestamp: "2025-01-15T09:53:34Z",
+		Level:     slog.LevelError,
+		LevelName: "ERROR",
+		Message:   "failed",
+		Attributes: attributesOf(
+			"error", errors.New("boom"),
+			"stack", "main.main()\n\t/app/main.go:12\n",
+		),
+	}
+
+	result, err := NewConsoleFormatter(false).Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-15 09:53:34.000 ERROR failed"+strings.Repeat(" ", 34)+"  error=boom\n"+
+		"    stack:\n      main.main()\n      \t/app/main.go:12", string(result))
+}
+
+func TestShouldColor(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "out")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	assert.True(t, shouldColor(ColorAlways, file))
+	assert.False(t, shouldColor(ColorNever, file))
+	// not a terminal
+	assert.False(t, shouldColor(ColorAuto, file))
+
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, shouldColor(ColorAuto, os.Stdout))
+	assert.True(t, shouldColor(ColorAlways, os.Stdout))
+}
+
+func TestCliAppender_ConsoleFormat(t *testing.T) {
+	log := &StructuredLog{Timestamp: "2025-01-15T09:53:34Z", Level: slog.LevelInfo, LevelName: "INFO", Message: "hello"}
+
+	appender := newCliAppender(&CliConfig{Friendly: true, Color: ColorNever, VerboseFormat: "."}, nil, levelNames{}, nil)
+	result, err := appender.Formatter().Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-01-15 09:53:34.000 INFO  hello", string(result))
+}
+
+func TestCliAppender_ConsoleCustomLevelWidth(t *testing.T) {
+	levels := newLevelNames(map[string]int{"NOTICE": int(LevelNotice), "TRACE": int(LevelTrace)})
+	appender := newCliAppender(&CliConfig{Friendly: true, Color: ColorNever, VerboseFormat: "."}, nil, levels, nil)
+
+	// the attributes start at the same column for the standard and the longer custom level names
+	var columns []int
+	for _, level := range []slog.Level{slog.LevelInfo, LevelNotice, slog.LevelError} {
+		log := &StructuredLog{Timestamp: "2025-01-15T09:53:34Z", Level: level, LevelName: levels.name(level), Message: "m", Attributes: attributesOf("n", 1)}
+		result, err := appender.Formatter().Format(log)
+		assert.NoError(t, err)
+		columns = append(columns, strings.Index(string(result), "n=1"))
+	}
+	assert.Equal(t, []int{columns[0], columns[0], columns[0]}, columns)
+
+	result, _ := appender.Formatter().Format(&StructuredLog{Timestamp: "2025-01-15T09:53:34Z", Level: LevelNotice, LevelName: "NOTICE", Message: "m"})
+	assert.Equal(t, "2025-01-15 09:53:34.000 NOTICE m", string(result))
+	result, _ = appender.Formatter().Format(&StructuredLog{Timestamp: "2025-01-15T09:53:34Z", Level: slog.LevelInfo, LevelName: "INFO", Message: "m"})
+	assert.Equal(t, "2025-01-15 09:53:34.000 INFO   m", string(result))
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// LogFields are the mango contract fields of a context, set all at once with WithLogFields
+type LogFields struct {
+	CorrelationID string
+	Operation     string
+	Application   string
+	Type          string
+
+	// Fields are custom context fields (MangoConfig.ContextFields) by name
+	// They are not returned by LogFieldsFrom, read them with ContextFieldFrom
+	Fields map[string]string
+}
+
+// WithCorrelationID returns a copy of ctx carrying the correlation id (CORRELATION_ID)
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, CORRELATION_ID, correlationID)
+}
+
+// WithOperation returns a copy of ctx carrying the operation (OPERATION)
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, OPERATION, operation)
+}
+
+// WithApplication returns a copy of ctx carrying the application (APPLICATION)
+func WithApplication(ctx context.Context, application string) context.Context {
+	return context.WithValue(ctx, APPLICATION, application)
+}
+
+// WithType returns a copy of ctx carrying the log type (TYPE), one of BusinessType, SecurityType or PerformanceType
+func WithType(ctx context.Context, logType string) context.Context {
+	return context.WithValue(ctx, TYPE, logType)
+}
+
+// WithLogFields returns a copy of ctx carrying the non-empty fields
+func WithLogFields(ctx context.Context, fields LogFields) context.Context {
+	for _, field := range []struct {
+		key   ctxKey
+		value string
+	}{
+		{CORRELATION_ID, fields.CorrelationID},
+		{OPERATION, fields.Operation},
+		{APPLICATION, fields.Application},
+		{TYPE, fields.Type},
+	} {
+		if field.value != "" {
+			ctx = context.WithValue(ctx, field.key, field.value)
+		}
+	}
+	for name, value := range fields.Fields {
+		ctx = WithContextField(ctx, name, value)
+	}
+	return ctx
+}
+
+// WithContextField returns a copy of ctx carrying the value of a custom context field declared in MangoConfig.ContextFields
+func WithContextField(ctx context.Context, name, value string) context.Context {
+	return context.WithValue(ctx, ctxKey(name), value)
+}
+
+// ContextFieldFrom returns the value of a custom context field of ctx, if any
+func ContextFieldFrom(ctx context.Context, name string) (string, bool) {
+	return contextString(ctx, ctxKey(name))
+}
+
+// CorrelationIDFrom returns the correlation id of ctx, if any
+func CorrelationIDFrom(ctx context.Context) (string, bool) {
+	return contextString(ctx, CORRELATION_ID)
+}
+
+// OperationFrom returns the operation of ctx, if any
+func OperationFrom(ctx context.Context) (string, bool) {
+	return contextString(ctx, OPERATION)
+}
+
+// ApplicationFrom returns the application of ctx, if any
+func ApplicationFrom(ctx context.Context) (string, bool) {
+	return contextString(ctx, APPLICATION)
+}
+
+// TypeFrom returns the log type of ctx, if any
+func TypeFrom(ctx context.Context) (string, bool) {
+	return contextString(ctx, TYPE)
+}
+
+// LogFieldsFrom returns the mango contract fields of ctx, empty when missing
+func LogFieldsFrom(ctx context.Context) LogFields {
+	var fields LogFields
+	fields.CorrelationID, _ = CorrelationIDFrom(ctx)
+	fields.Operation, _ = OperationFrom(ctx)
+	fields.Application, _ = ApplicationFrom(ctx)
+	fields.Type, _ = TypeFrom(ctx)
+	return fields
+}
+
+// contextString reads the value of key as a string.
+// Besides plain strings, named string types (e.g. type Operation string) and fmt.Stringer values are accepted.
+func contextString(ctx context.Context, key ctxKey) (string, bool) {
+	switch value := ctx.Value(key).(type) {
+	case nil:
+		return "", false
+	case string:
+		return value, true
+	case fmt.Stringer:
+		return value.String(), true
+	default:
+		if v := reflect.ValueOf(value); v.Kind() == reflect.String {
+			return v.String(), true
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// contextContract is what a logger requires from the log context, computed once from its MangoConfig at construction.
+// It is never modified afterwards, so it is shared by the derived handlers and safe for concurrent use.
+type contextContract struct {
+	// strict fails logs missing a required field or with a TYPE outside allowedTypes
+	strict bool
+
+	// required are the context fields read into each log, validated when strict (CORRELATION_ID on its own strictness)
+	required []ctxKey
+
+	// allowedTypes are the values accepted for TYPE in strict mode
+	allowedTypes []string
+
+	// fields are the custom context fields of MangoConfig.ContextFields
+	fields []ContextFieldConfig
+
+	// correlationId settings applied when CORRELATION_ID is missing
+	autoGenerate bool
+	fromTraceId  bool
+}
+
+// newContextContract copies REQUIRED_FIELDS and ALLOWED_TYPES (unless MangoConfig.AllowedTypes is set),
+// so later changes to the package defaults don't affect existing loggers
+func newContextContract(config *MangoConfig) *contextContract {
+	contract := &contextContract{
+		strict:       config.Strict,
+		required:     slices.Clone(REQUIRED_FIELDS),
+		allowedTypes: slices.Clone(ALLOWED_TYPES),
+		autoGenerate: config.CorrelationId.AutoGenerate,
+		fromTraceId:  config.CorrelationId.FromTraceId,
+	}
+	for _, field := range config.ContextFields {
+		field.AllowedValues = slices.Clone(field.AllowedValues)
+		contract.fields = append(contract.fields, field)
+	}
+	if len(config.AllowedTypes) > 0 {
+		contract.allowedTypes = slices.Clone(config.AllowedTypes)
+	}
+	if config.CorrelationId.Strict && !slices.Contains(contract.required, CORRELATION_ID) {
+		contract.required = append(contract.required, CORRELATION_ID)
+	}
+	return contract
+}
+
+// fieldNames are the names of the custom context fields
+func (c *contextContract) fieldNames() []string {
+	names := make([]string, len(c.fields))
+	for i, field := range c.fields {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// handleContextFields copies the custom context fields of ctx (or their default) into the log, validating them in strict mode
+func (c *contextContract) handleContextFields(ctx context.Context, logOutput *StructuredLog) error {
+	for _, field := range c.fields {
+		value, ok := contextString(ctx, ctxKey(field.Name))
+		if !ok && field.Default != "" {
+			value, ok = field.Default, true
+		}
+		if !ok {
+			if c.strict && field.Required {
+				return fmt.Errorf("%w - [%s] required in context and not present (or wrong type - expected string). This can be added by doing: mangologger.WithContextField(newCtx, %q, \"desiredValue\")", errStrictModeOn, field.Name, field.Name)
+			}
+			continue
+		}
+		if c.strict && len(field.AllowedValues) > 0 && !slices.Contains(field.AllowedValues, value) {
+			return fmt.Errorf("%w - [%s] Current value [%s] is not in the allowed list: %+q", errStrictModeOn, field.Name, value, field.AllowedValues)
+		}
+		if logOutput.Fields == nil {
+			logOutput.Fields = make(map[string]string, len(c.fields))
+		}
+		logOutput.Fields[field.Name] = value
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextContract_PerLogger(t *testing.T) {
+	requiredBefore := slices.Clone(REQUIRED_FIELDS)
+
+	strictCorrelation, _ := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{Strict: true, CorrelationId: &CorrelationIdConfig{Strict: true}}}, slog.LevelInfo, nil)
+	lenient, lenientBuf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{Strict: true}}, slog.LevelInfo, nil)
+
+	ctx := WithLogFields(context.Background(), LogFields{Operation: "op", Application: "app", Type: BusinessType})
+	for range 10 {
+		assert.ErrorIs(t, strictCorrelation.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "no correlation", 0)), errStrictModeOn)
+	}
+	assert.Equal(t, requiredBefore, REQUIRED_FIELDS)
+	assert.Equal(t, []ctxKey{TYPE, APPLICATION, OPERATION, CORRELATION_ID}, strictCorrelation.contract.required)
+
+	// the other logger doesn't require a correlation id
+	assert.NoError(t, lenient.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "no correlation needed", 0)))
+	assert.Contains(t, lenientBuf.String(), "no correlation needed")
+}
+
+func TestContextContract_AllowedTypes(t *testing.T) {
+	custom, buf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{Strict: true, AllowedTypes: []string{"Audit"}}}, slog.LevelInfo, nil)
+	defaults, _ := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{Strict: true}}, slog.LevelInfo, nil)
+
+	ctx := WithLogFields(context.Background(), LogFields{Operation: "op", Application: "app", Type: "Audit"})
+	assert.NoError(t, custom.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "audited", 0)))
+	assert.Contains(t, buf.String(), `"type":"Audit"`)
+	assert.ErrorIs(t, defaults.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "audited", 0)), errStrictModeOn)
+
+	// the package defaults are copied at construction
+	original := ALLOWED_TYPES
+	defer func() { ALLOWED_TYPES = original }()
+	ALLOWED_TYPES = []string{"Audit"}
+	assert.ErrorIs(t, defaults.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "audited", 0)), errStrictModeOn)
+}
+
+func TestContextContract_ConcurrentLogging(t *testing.T) {
+	strict, strictBuf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{Strict: true, CorrelationId: &CorrelationIdConfig{Strict: true}}}, slog.LevelInfo, nil)
+	lenient, lenientBuf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{CorrelationId: &CorrelationIdConfig{AutoGenerate: true}}}, slog.LevelInfo, nil)
+
+	const goroutines, logs = 50, 20
+	var wg sync.WaitGroup
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := WithLogFields(context.Background(), LogFields{
+				CorrelationID: fmt.Sprintf("corr-%d", g),
+				Operation:     "op",
+				Application:   "app",
+				Type:          BusinessType,
+			})
+			strictLogger := slog.New(strict).With("goroutine", g)
+			lenientLogger := slog.New(lenient).WithGroup("worker").With("goroutine", g)
+			for i := range logs {
+				strictLogger.InfoContext(ctx, "strict", "i", i)
+				lenientLogger.Info("lenient", "i", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, goroutines*logs, strings.Count(strictBuf.String(), "\n"))
+	assert.Equal(t, goroutines*logs, strings.Count(lenientBuf.String(), "\n"))
+	assert.Len(t, strict.contract.required, 4)
+	assert.Len(t, REQUIRED_FIELDS, 3)
+}
+
+func TestContextContract_ContextFields(t *testing.T) {
+	mango := &MangoConfig{
+		Strict: true,
+		ContextFields: []ContextFieldConfig{
+			{Name: "tenant", Required: true},
+			{Name: "environment", AllowedValues: []string{"dev", "prod"}, Default: "prod"},
+			{Name: "user.id"},
+		},
+	}
+	logger, buf := newBufferedLogger(t, &LogConfig{MangoConfig: mango}, slog.LevelInfo, nil)
+	ctx := WithLogFields(context.Background(), LogFields{Operation: "op", Application: "app", Type: BusinessType})
+
+	err := logger.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "no tenant", 0))
+	assert.ErrorIs(t, err, errStrictModeOn)
+	assert.ErrorContains(t, err, `mangologger.WithContextField(newCtx, "tenant", "desiredValue")`)
+
+	err = logger.Handle(WithContextField(WithContextField(ctx, "tenant", "acme"), "environment", "qa"), slog.NewRecord(time.Now(), slog.LevelInfo, "wrong environment", 0))
+	assert.ErrorIs(t, err, errStrictModeOn)
+	assert.ErrorContains(t, err, "[environment] Current value [qa] is not in the allowed list")
+
+	// the config is copied at construction
+	mango.ContextFields[0].Required = false
+
+	ctx = WithLogFields(ctx, LogFields{Fields: map[string]string{"tenant": "acme"}})
+	value, ok := ContextFieldFrom(ctx, "tenant")
+	assert.True(t, ok)
+	assert.Equal(t, "acme", value)
+	assert.NoError(t, logger.Handle(ctx, slog.NewRecord(time.Now(), slog.LevelInfo, "tenant set", 0)))
+	assert.Contains(t, buf.String(), `"logId":`)
+	assert.Contains(t, buf.String(), `"environment":"prod","tenant":"acme","level":"INFO","message":"tenant set","attributes":{}`)
+	assert.NotContains(t, buf.String(), "user.id")
+
+	// not validated outside strict mode
+	lenient, lenientBuf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{ContextFields: []ContextFieldConfig{
+		{Name: "tenant", Required: true, AllowedValues: []string{"acme"}},
+	}}}, slog.LevelInfo, nil)
+	assert.NoError(t, lenient.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "no tenant", 0)))
+	assert.NoError(t, lenient.Handle(WithContextField(context.Background(), "tenant", "other"), slog.NewRecord(time.Now(), slog.LevelInfo, "other tenant", 0)))
+	assert.Contains(t, lenientBuf.String(), `"tenant":"other"`)
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testOperation string
+
+type testStringer struct{ name string }
+
+func (s testStringer) String() string { return s.name }
+
+func TestContextHelpers(t *testing.T) {
+	ctx := context.Background()
+	_, ok := CorrelationIDFrom(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, LogFields{}, LogFieldsFrom(ctx))
+
+	ctx = WithCorrelationID(ctx, "corr")
+	ctx = WithOperation(ctx, "checkout")
+	ctx = WithApplication(ctx, "orders-api")
+	ctx = WithType(ctx, BusinessType)
+
+	correlationID, ok := CorrelationIDFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "corr", correlationID)
+	assert.Equal(t, LogFields{CorrelationID: "corr", Operation: "checkout", Application: "orders-api", Type: BusinessType}, LogFieldsFrom(ctx))
+
+	// helpers and raw context keys are interchangeable
+	assert.Equal(t, "checkout", ctx.Value(OPERATION))
+	operation, _ := OperationFrom(context.WithValue(ctx, OPERATION, "raw"))
+	assert.Equal(t, "raw", operation)
+}
+
+func TestWithLogFields(t *testing.T) {
+	ctx := WithLogFields(WithApplication(context.Background(), "kept"), LogFields{CorrelationID: "corr", Type: SecurityType})
+
+	assert.Equal(t, LogFields{CorrelationID: "corr", Application: "kept", Type: SecurityType}, LogFieldsFrom(ctx))
+	_, ok := OperationFrom(ctx)
+	assert.False(t, ok)
+}
+
+func TestContextString_TypedValues(t *testing.T) {
+	ctx := context.WithValue(context.Background(), OPERATION, testOperation("typed"))
+	ctx = context.WithValue(ctx, APPLICATION, testStringer{name: "stringer"})
+	ctx = context.WithValue(ctx, TYPE, 42)
+
+	operation, ok := OperationFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "typed", operation)
+	application, ok := ApplicationFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "stringer", application)
+	_, ok = TypeFrom(ctx)
+	assert.False(t, ok)
+}
+
+func TestMangoLogger_TypedContextValues(t *testing.T) {
+	logger := newTestLogger(true, false, true, true)
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "typed", 0)
+
+	ctx := WithLogFields(context.Background(), LogFields{CorrelationID: "corr", Application: "app", Type: BusinessType})
+	ctx = context.WithValue(ctx, OPERATION, testOperation("typed-op"))
+
+	logOutput, err := logger.buildLog(ctx, record)
+	assert.NoError(t, err)
+	assert.Equal(t, "typed-op", logOutput.Operation)
+	assert.Equal(t, "app", logOutput.Application)
+	assert.Equal(t, BusinessType, logOutput.Type)
+	assert.Equal(t, "corr", logOutput.Correlationid)
+}
@@ -0,0 +1,335 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Encoding is the schema a built-in output renders the StructuredLog with
+type Encoding string
+
+const (
+	// EncodingMangoJSON is the StructuredLog json contract (default)
+	EncodingMangoJSON Encoding = "mango-json"
+
+	// EncodingLogfmt renders key=value pairs, attributes being flattened with dotted keys
+	EncodingLogfmt Encoding = "logfmt"
+
+	// EncodingECS renders json with the Elastic Common Schema field names
+	EncodingECS Encoding = "ecs"
+
+	// EncodingOTel renders json with the OTLP/JSON log record field names (timeUnixNano, severityText, body...)
+	EncodingOTel Encoding = "otel"
+)
+
+// ECSVersion is the ecs.version emitted by EncodingECS
+const ECSVersion = "8.11.0"
+
+// logField is a top-level field of the StructuredLog by its mango json name
+type logField struct {
+	name      string
+	value     any
+	omitEmpty bool
+}
+
+// omitted reports whether the field is left out of the output, being optional and empty
+func (f logField) omitted() bool {
+	return f.omitEmpty && (f.value == "" || f.value == (*slog.Source)(nil))
+}
+
+// fields are the top-level fields of the log in the mango json order, attributes excluded
+// The custom context fields come after logId, sorted by name
+func (l *StructuredLog) fields() []logField {
+	fields := []logField{
+		{name: "ts", value: l.Timestamp},
+		{name: "type", value: l.Type},
+		{name: "application", value: l.Application},
+		{name: "operation", value: l.Operation},
+		{name: "correlationid", value: l.Correlationid},
+		{name: "traceId", value: l.TraceId, omitEmpty: true},
+		{name: "spanId", value: l.SpanId, omitEmpty: true},
+		{name: "traceFlags", value: l.TraceFlags, omitEmpty: true},
+		{name: "logId", value: l.LogId},
+	}
+	names := make([]string, 0, len(l.Fields))
+	for name := range l.Fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fields = append(fields, logField{name: name, value: l.Fields[name]})
+	}
+	return append(fields,
+		logField{name: "level", value: l.LevelName},
+		logField{name: "message", value: l.Message},
+		logField{name: "source", value: l.Source, omitEmpty: true},
+		logField{name: "stack", value: l.Stack, omitEmpty: true},
+	)
+}
+
+// standardFieldNames are the mango json names of the StructuredLog fields, custom context fields excluded
+var standardFieldNames = func() []string {
+	names := []string{"attributes"}
+	for _, field := range (&StructuredLog{}).fields() {
+		names = append(names, field.name)
+	}
+	return names
+}()
+
+// fieldPlacement is where an encoding puts a StructuredLog field: the name within the section, "" being the top level
+// An empty name inlines the (attributes) map into the section
+type fieldPlacement struct {
+	section string
+	name    string
+}
+
+// encodingPlacements are the non-identity placements of each encoding, every other field keeping its mango name at the top level
+// (or with encodingCustomPlacements for the custom context fields)
+var encodingPlacements = map[Encoding]map[string]fieldPlacement{
+	EncodingMangoJSON: {},
+	EncodingLogfmt: {
+		"attributes": {name: ""},
+	},
+	EncodingECS: {
+		"ts":            {name: "@timestamp"},
+		"type":          {name: "labels.type"},
+		"application":   {name: "service.name"},
+		"operation":     {name: "event.action"},
+		"correlationid": {name: "labels.correlation_id"},
+		"traceId":       {name: "trace.id"},
+		"spanId":        {name: "span.id"},
+		"traceFlags":    {name: "labels.trace_flags"},
+		"logId":         {name: "event.id"},
+		"level":         {name: "log.level"},
+		"source":        {name: ""},
+		"stack":         {name: "error.stack_trace"},
+	},
+	EncodingOTel: {
+		"ts":            {name: "timeUnixNano"},
+		"type":          {section: "attributes", name: "log.type"},
+		"application":   {section: "resource", name: "service.name"},
+		"operation":     {section: "attributes", name: "operation"},
+		"correlationid": {section: "attributes", name: "correlationid"},
+		"traceId":       {name: "traceId"},
+		"spanId":        {name: "spanId"},
+		"traceFlags":    {name: "flags"},
+		"logId":         {section: "attributes", name: "log.record.uid"},
+		"level":         {name: "severityText"},
+		"message":       {name: "body"},
+		"source":        {section: "attributes", name: ""},
+		"stack":         {section: "attributes", name: "exception.stacktrace"},
+		"attributes":    {section: "attributes", name: ""},
+	},
+}
+
+// encodingCustomPlacements place the custom context fields, when not at the top level under their own name
+var encodingCustomPlacements = map[Encoding]func(field string) fieldPlacement{
+	EncodingECS: func(field string) fieldPlacement {
+		return fieldPlacement{name: "labels." + field}
+	},
+	EncodingOTel: func(field string) fieldPlacement {
+		return fieldPlacement{section: "attributes", name: field}
+	},
+}
+
+// encodingSourceNames are the names of the source function, file and line, nested in the source field unless renamed
+var encodingSourceNames = map[Encoding][3]string{
+	EncodingLogfmt: {"function", "file", "line"},
+	EncodingECS:    {"log.origin.function", "log.origin.file.name", "log.origin.file.line"},
+	EncodingOTel:   {"code.function", "code.filepath", "code.lineno"},
+}
+
+// encoder renders the StructuredLog with an Encoding and the configured field names
+type encoder struct {
+	encoding   Encoding
+	placements map[string]fieldPlacement
+}
+
+// NewEncoder creates the Formatter of the encoding (EncodingMangoJSON when empty)
+// fieldNames renames the StructuredLog fields, keyed by their mango json name (ts, correlationid, logId...), on top of the encoding's own names.
+// Renamed fields stay in the section the encoding puts them in, and renaming attributes to "" inlines them.
+func NewEncoder(encoding Encoding, fieldNames map[string]string) (Formatter, error) {
+	return newEncoder(encoding, fieldNames, nil)
+}
+
+// newEncoder creates the encoder, the custom context fields being valid keys of fieldNames
+func newEncoder(encoding Encoding, fieldNames map[string]string, customFields []string) (Formatter, error) {
+	if encoding == "" {
+		encoding = EncodingMangoJSON
+	}
+	placements, ok := encodingPlacements[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+	if err := checkFieldNames(fieldNames, customFields); err != nil {
+		return nil, err
+	}
+	if encoding == EncodingMangoJSON && len(fieldNames) == 0 {
+		return JSONFormatter, nil
+	}
+
+	e := &encoder{encoding: encoding, placements: make(map[string]fieldPlacement, len(placements)+len(fieldNames))}
+	for field, placement := range placements {
+		e.placements[field] = placement
+	}
+	for field, name := range fieldNames {
+		placement := e.place(field)
+		placement.name = name
+		e.placements[field] = placement
+	}
+	return e, nil
+}
+
+// newOutputEncoder is the encoder of a built-in output, JSONFormatter for an unknown encoding or field name
+func newOutputEncoder(encoding Encoding, fieldNames map[string]string, customFields []string) Formatter {
+	formatter, err := newEncoder(encoding, fieldNames, customFields)
+	if err != nil {
+		return JSONFormatter
+	}
+	return formatter
+}
+
+// checkFieldNames validates the renaming map against the StructuredLog fields
+func checkFieldNames(fieldNames map[string]string, customFields []string) error {
+	known := slices.Concat(standardFieldNames, customFields)
+	var errs []string
+	for field, name := range fieldNames {
+		switch {
+		case !slices.Contains(known, field):
+			errs = append(errs, fmt.Sprintf("unknown field %q", field))
+		case name == "" && field != "attributes" && field != "source":
+			errs = append(errs, fmt.Sprintf("field %q renamed to an empty name", field))
+		}
+	}
+	if len(errs) > 0 {
+		slices.Sort(errs)
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+func (e *encoder) place(field string) fieldPlacement {
+	if placement, ok := e.placements[field]; ok {
+		return placement
+	}
+	if custom, ok := encodingCustomPlacements[e.encoding]; ok && !slices.Contains(standardFieldNames, field) {
+		return custom(field)
+	}
+	return fieldPlacement{name: field}
+}
+
+func (e *encoder) Format(log *StructuredLog) ([]byte, error) {
+	root := &OrderedMap{}
+	for _, field := range log.fields() {
+		if field.omitted() {
+			continue
+		}
+		if names, ok := encodingSourceNames[e.encoding]; ok && field.name == "source" {
+			source := &OrderedMap{}
+			source.Set(names[0], log.Source.Function)
+			source.Set(names[1], log.Source.File)
+			source.Set(names[2], log.Source.Line)
+			field.value = source
+		}
+		if e.encoding == EncodingOTel {
+			switch field.name {
+			case "ts":
+				// OTLP/JSON writes the 64-bit nanoseconds since the epoch as a decimal string
+				field.value = strconv.FormatInt(logTime(log).UnixNano(), 10)
+			case "traceFlags":
+				field.value = otelFlags(log.TraceFlags)
+			}
+		}
+		e.set(root, field.name, field.value)
+		switch {
+		case e.encoding == EncodingOTel && field.name == "level":
+			root.Set("severityNumber", otelSeverity(log.Level))
+		case e.encoding == EncodingECS && field.name == "message":
+			root.Set("ecs.version", ECSVersion)
+		}
+	}
+	e.set(root, "attributes", log.Attributes)
+
+	if e.encoding == EncodingLogfmt {
+		return root.logfmt(), nil
+	}
+	return json.Marshal(root)
+}
+
+// set places the value of the field in its section, inlining the OrderedMaps placed without a name
+func (e *encoder) set(root *OrderedMap, field string, value any) {
+	placement := e.place(field)
+	target := root
+	if placement.section != "" {
+		existing, _ := root.Get(placement.section)
+		section, ok := existing.(*OrderedMap)
+		if !ok {
+			section = &OrderedMap{}
+			root.Set(placement.section, section)
+		}
+		target = section
+	}
+	if placement.name != "" {
+		target.Set(placement.name, value)
+		return
+	}
+	attributes, _ := value.(*OrderedMap)
+	for _, key := range attributes.Keys() {
+		// inlined attributes never overwrite the log fields
+		if _, ok := target.Get(key); !ok {
+			value, _ := attributes.Get(key)
+			target.Set(key, value)
+		}
+	}
+}
+
+// otelSeverity maps the slog level to the OpenTelemetry severity number, DEBUG being 5, INFO 9, WARN 13 and ERROR 17
+func otelSeverity(level slog.Level) int {
+	return min(max(int(level)+9, 1), 24)
+}
+
+// otelFlags is the OTLP flags number of the hex W3C trace flags, 0 when not valid
+func otelFlags(traceFlags string) uint64 {
+	flags, err := strconv.ParseUint(traceFlags, 16, 8)
+	if err != nil {
+		return 0
+	}
+	return flags
+}
+
+// logfmt renders the object as key=value pairs, nested objects and maps being flattened with dotted keys
+func (o *OrderedMap) logfmt() []byte {
+	var b strings.Builder
+	o.writeLogfmt(&b, "")
+	return []byte(b.String())
+}
+
+func (o *OrderedMap) writeLogfmt(b *strings.Builder, prefix string) {
+	for _, key := range o.keys {
+		for _, attr := range flattenValue(prefix+key, o.values[key]) {
+			writeLogfmtPair(b, attr.key, attr.value)
+		}
+	}
+}
+
+func writeLogfmtPair(b *strings.Builder, key string, value any) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(logfmtValue(value))
+}
+
+// logfmtValue is the console rendering of the value, with multiline strings and json values containing spaces or quotes quoted as well
+func logfmtValue(value any) string {
+	s := consoleValue(value)
+	if strings.Contains(s, "\n") || !strings.HasPrefix(s, `"`) && s != quoteIfNeeded(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
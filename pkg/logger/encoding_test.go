@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newEncodingTestLog() *StructuredLog {
+	return &StructuredLog{
+		Timestamp:     "2025-01-15T09:53:34.717Z",
+		Type:          BusinessType,
+		Application:   "checkout-api",
+		Operation:     "cart-create",
+		Correlationid: "abc",
+		LogId:         "id-1",
+		Level:         slog.LevelWarn,
+		LevelName:     "WARN",
+		Message:       "cart created",
+		Attributes: attributesOf(
+			"items", 3,
+			"note", "two words",
+			"request", map[string]interface{}{"status": 200},
+		),
+	}
+}
+
+func TestNewEncoder_MangoJSON(t *testing.T) {
+	log := newEncodingTestLog()
+
+	formatter, err := NewEncoder("", nil)
+	assert.NoError(t, err)
+	result, err := formatter.Format(log)
+	assert.NoError(t, err)
+	expected, _ := JSONFormatter.Format(log)
+	assert.Equal(t, string(expected), string(result))
+
+	formatter, err = NewEncoder(EncodingMangoJSON, map[string]string{"ts": "@timestamp", "attributes": ""})
+	assert.NoError(t, err)
+	result, err = formatter.Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"@timestamp":"2025-01-15T09:53:34.717Z","type":"Business","application":"checkout-api","operation":"cart-create",`+
+		`"correlationid":"abc","logId":"id-1","level":"WARN","message":"cart created","items":3,"note":"two words","request":{"status":200}}`, string(result))
+}
+
+func TestNewEncoder_Logfmt(t *testing.T) {
+	log := newEncodingTestLog()
+	log.Attributes.Set("stack", "line 1\nline 2")
+	log.Attributes.Set("tags", []string{"a b"})
+
+	formatter, err := NewEncoder(EncodingLogfmt, map[string]string{"level": "lvl"})
+	assert.NoError(t, err)
+	result, err := formatter.Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, `ts=2025-01-15T09:53:34.717Z type=Business application=checkout-api operation=cart-create correlationid=abc logId=id-1 `+
+		`lvl=WARN message="cart created" items=3 note="two words" request.status=200 stack="line 1\nline 2" tags="[\"a b\"]"`, string(result))
+}
+
+func TestNewEncoder_ECS(t *testing.T) {
+	formatter, err := NewEncoder(EncodingECS, map[string]string{"correlationid": "trace.id"})
+	assert.NoError(t, err)
+	result, err := formatter.Format(newEncodingTestLog())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"@timestamp":"2025-01-15T09:53:34.717Z","labels.type":"Business","service.name":"checkout-api","event.action":"cart-create",`+
+		`"trace.id":"abc","event.id":"id-1","log.level":"WARN","message":"cart created","ecs.version":"`+ECSVersion+`",`+
+		`"attributes":{"items":3,"note":"two words","request":{"status":200}}}`, string(result))
+}
+
+func TestNewEncoder_OTel(t *testing.T) {
+	log := newEncodingTestLog()
+	// inlined attributes don't overwrite the log fields
+	log.Attributes.Set("operation", "overwritten")
+
+	formatter, err := NewEncoder(EncodingOTel, nil)
+	assert.NoError(t, err)
+	result, err := formatter.Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"timeUnixNano":"1736934814717000000","attributes":{"log.type":"Business","operation":"cart-create","correlationid":"abc",`+
+		`"log.record.uid":"id-1","items":3,"note":"two words","request":{"status":200}},"resource":{"service.name":"checkout-api"},`+
+		`"severityText":"WARN","severityNumber":13,"body":"cart created"}`, string(result))
+
+	// the OTLP/JSON key set, the 64-bit nanoseconds since the epoch being a decimal string
+	log.TraceId, log.SpanId, log.TraceFlags = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", "01"
+	result, err = formatter.Format(log)
+	assert.NoError(t, err)
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(result, &decoded))
+	keys := make([]string, 0, len(decoded))
+	for key := range decoded {
+		keys = append(keys, key)
+	}
+	assert.ElementsMatch(t, []string{"timeUnixNano", "traceId", "spanId", "flags", "severityText", "severityNumber", "body", "attributes", "resource"}, keys)
+	assert.Equal(t, strconv.FormatInt(time.Date(2025, 1, 15, 9, 53, 34, 717000000, time.UTC).UnixNano(), 10), decoded["timeUnixNano"])
+	assert.Equal(t, float64(1), decoded["flags"])
+
+	assert.Equal(t, 1, otelSeverity(slog.Level(-20)))
+	assert.Equal(t, 5, otelSeverity(slog.LevelDebug))
+	assert.Equal(t, 17, otelSeverity(slog.LevelError))
+	assert.Equal(t, 21, otelSeverity(slog.LevelError+4))
+	assert.Equal(t, 24, otelSeverity(slog.Level(40)))
+}
+
+func TestNewEncoder_Invalid(t *testing.T) {
+	_, err := NewEncoder("xml", nil)
+	assert.ErrorContains(t, err, `unknown encoding "xml"`)
+
+	_, err = NewEncoder(EncodingECS, map[string]string{"user": "user.id", "level": ""})
+	assert.ErrorContains(t, err, `field "level" renamed to an empty name, unknown field "user"`)
+
+	// falls back to json
+	result, err := newOutputEncoder("xml", nil, nil).Format(&StructuredLog{LevelName: "INFO"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(result), `"level":"INFO"`)
+}
+
+func TestMangoLogger_FileEncoding(t *testing.T) {
+	logger, err := New(&LogConfig{Out: &OutConfig{Enabled: true, File: &FileOutputConfig{Enabled: true, Encoding: EncodingLogfmt}}})
+	assert.NoError(t, err)
+
+	for _, appender := range logger.Appenders() {
+		if file, ok := appender.(*fileAppender); ok {
+			result, err := file.Formatter().Format(&StructuredLog{LevelName: "INFO", Message: "hello"})
+			assert.NoError(t, err)
+			assert.Equal(t, `ts="" type="" application="" operation="" correlationid="" logId="" level=INFO message=hello`, string(result))
+		}
+	}
+}
+
+func TestNewEncoder_TraceFields(t *testing.T) {
+	log := &StructuredLog{LevelName: "INFO", TraceId: "4bf92f3577b34da6a3ce929d0e0e4736", SpanId: "00f067aa0ba902b7", TraceFlags: "01"}
+
+	for encoding, expected := range map[Encoding]string{
+		EncodingMangoJSON: `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"00f067aa0ba902b7","traceFlags":"01"`,
+		EncodingECS:       `"trace.id":"4bf92f3577b34da6a3ce929d0e0e4736","span.id":"00f067aa0ba902b7","labels.trace_flags":"01"`,
+		EncodingOTel:      `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736","spanId":"00f067aa0ba902b7","flags":1`,
+		EncodingLogfmt:    `traceId=4bf92f3577b34da6a3ce929d0e0e4736 spanId=00f067aa0ba902b7 traceFlags=01`,
+	} {
+		formatter, err := NewEncoder(encoding, nil)
+		assert.NoError(t, err)
+		result, err := formatter.Format(log)
+		assert.NoError(t, err)
+		assert.Contains(t, string(result), expected, encoding)
+	}
+}
+
+func TestNewEncoder_CustomFields(t *testing.T) {
+	log := newEncodingTestLog()
+	log.Fields = map[string]string{"tenant": "acme", "environment": "prod"}
+
+	result, err := JSONFormatter.Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ts":"2025-01-15T09:53:34.717Z","type":"Business","application":"checkout-api","operation":"cart-create",`+
+		`"correlationid":"abc","logId":"id-1","environment":"prod","tenant":"acme","level":"WARN","message":"cart created",`+
+		`"attributes":{"items":3,"note":"two words","request":{"status":200}}}`, string(result))
+
+	formatter, err := newEncoder(EncodingECS, map[string]string{"tenant": "organization.id"}, []string{"tenant", "environment"})
+	assert.NoError(t, err)
+	result, err = formatter.Format(log)
+	assert.NoError(t, err)
+	assert.Contains(t, string(result), `"labels.environment":"prod","organization.id":"acme","log.level":"WARN"`)
+
+	formatter, err = NewEncoder(EncodingOTel, nil)
+	assert.NoError(t, err)
+	result, err = formatter.Format(log)
+	assert.NoError(t, err)
+	assert.Contains(t, string(result), `"log.record.uid":"id-1","environment":"prod","tenant":"acme","items":3`)
+
+	// custom fields can only be renamed when declared
+	_, err = NewEncoder(EncodingECS, map[string]string{"tenant": "organization.id"})
+	assert.ErrorContains(t, err, `unknown field "tenant"`)
+}
@@ -0,0 +1,46 @@
+package logger
+
+import (
+	"log/slog"
+
+	"github.com/natefinch/lumberjack"
+)
+
+// fileAppender is the built-in Appender writing newline delimited entries to a rotating file as configured by FileOutputConfig
+type fileAppender struct {
+	config    *FileOutputConfig
+	writer    *lumberjack.Logger
+	level     *outputLevel
+	formatter Formatter
+}
+
+func (a *fileAppender) Enabled() bool {
+	return a.config.Enabled
+}
+
+// Level is FileOutputConfig.Level, or DEBUG when FileOutputConfig.Debug is set and INFO otherwise
+func (a *fileAppender) Level() slog.Level {
+	return a.level.Level()
+}
+
+// Formatter encodes with FileOutputConfig.Encoding, JSONFormatter when not set
+func (a *fileAppender) Formatter() Formatter {
+	if a.formatter == nil {
+		return JSONFormatter
+	}
+	return a.formatter
+}
+
+func (a *fileAppender) Append(_ *StructuredLog, formatted []byte) error {
+	return a.write(formatted)
+}
+
+func (a *fileAppender) write(b []byte) error {
+	_, err := a.writer.Write(append(b, '\n'))
+	return err
+}
+
+// Close the log file, it is reopened on the next write
+func (a *fileAppender) Close() error {
+	return a.writer.Close()
+}
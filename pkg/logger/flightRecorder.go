@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"container/list"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// flightRecorder buffers the records no output writes per correlation id, in least recently used order
+type flightRecorder struct {
+	level   slog.Level
+	trigger slog.Level
+	size    int
+	max     int
+
+	mu sync.Mutex
+	// recordings by correlation id, the least recently used being evicted first
+	recordings map[string]*list.Element
+	lru        *list.List
+
+	evicted atomic.Uint64
+}
+
+// recording is the ring buffer of a correlation id
+type recording struct {
+	correlationId string
+	logs          []*StructuredLog
+	next          int
+	full          bool
+}
+
+// newFlightRecorder creates the recorder, nil when disabled
+func newFlightRecorder(config *FlightRecorderConfig, levels levelNames) *flightRecorder {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	r := &flightRecorder{
+		size:       config.Size,
+		max:        config.MaxCorrelations,
+		recordings: make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+	if r.size <= 0 {
+		r.size = DefaultFlightRecorderSize
+	}
+	if r.max <= 0 {
+		r.max = DefaultFlightRecorderMaxCorrelations
+	}
+	r.level = slog.LevelDebug
+	if level, err := levels.parse(config.Level); err == nil && config.Level != "" {
+		r.level = level
+	}
+	r.trigger = slog.LevelError
+	if level, err := levels.parse(config.TriggerLevel); err == nil && config.TriggerLevel != "" {
+		r.trigger = level
+	}
+	return r
+}
+
+// records reports whether records of the level are kept when no output writes them
+func (r *flightRecorder) records(level slog.Level) bool {
+	return r != nil && level >= r.level && level < r.trigger
+}
+
+// triggers reports whether records of the level flush the recording of their correlation id
+func (r *flightRecorder) triggers(level slog.Level) bool {
+	return r != nil && level >= r.trigger
+}
+
+// record keeps the log, overwriting the oldest one of the correlation id when its buffer is full
+func (r *flightRecorder) record(correlationId string, log *StructuredLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	element, ok := r.recordings[correlationId]
+	if ok {
+		r.lru.MoveToFront(element)
+	} else {
+		if r.lru.Len() >= r.max {
+			oldest := r.lru.Remove(r.lru.Back()).(*recording)
+			delete(r.recordings, oldest.correlationId)
+			r.evicted.Add(uint64(oldest.len()))
+		}
+		element = r.lru.PushFront(&recording{correlationId: correlationId, logs: make([]*StructuredLog, r.size)})
+		r.recordings[correlationId] = element
+	}
+
+	rec := element.Value.(*recording)
+	if rec.full {
+		r.evicted.Add(1)
+	}
+	rec.logs[rec.next] = log
+	rec.next = (rec.next + 1) % len(rec.logs)
+	rec.full = rec.full || rec.next == 0
+}
+
+// take removes the recording of the correlation id, returning its logs oldest first
+func (r *flightRecorder) take(correlationId string) []*StructuredLog {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	element, ok := r.recordings[correlationId]
+	if !ok {
+		return nil
+	}
+	delete(r.recordings, correlationId)
+	rec := r.lru.Remove(element).(*recording)
+	if !rec.full {
+		return rec.logs[:rec.next]
+	}
+	return append(rec.logs[rec.next:], rec.logs[:rec.next]...)
+}
+
+func (rec *recording) len() int {
+	if rec.full {
+		return len(rec.logs)
+	}
+	return rec.next
+}
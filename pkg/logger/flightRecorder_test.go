@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func recordedMessages(logs []*StructuredLog) []string {
+	var messages []string
+	for _, log := range logs {
+		messages = append(messages, log.Message.(string))
+	}
+	return messages
+}
+
+func TestFlightRecorder_Buffers(t *testing.T) {
+	recorder := newFlightRecorder(&FlightRecorderConfig{Enabled: true, Size: 3, MaxCorrelations: 2}, newLevelNames(nil))
+	for _, message := range []string{"1", "2", "3", "4", "5"} {
+		recorder.record("a", &StructuredLog{Message: message})
+	}
+	recorder.record("b", &StructuredLog{Message: "b1"})
+	// the oldest records are overwritten
+	assert.Equal(t, []string{"3", "4", "5"}, recordedMessages(recorder.take("a")))
+	assert.Nil(t, recorder.take("a"))
+	assert.Equal(t, uint64(2), recorder.evicted.Load())
+
+	// the least recently used correlation id is evicted
+	recorder.record("c", &StructuredLog{Message: "c1"})
+	recorder.record("b", &StructuredLog{Message: "b2"})
+	recorder.record("d", &StructuredLog{Message: "d1"})
+	assert.Nil(t, recorder.take("c"))
+	assert.Equal(t, []string{"b1", "b2"}, recordedMessages(recorder.take("b")))
+	assert.Equal(t, []string{"d1"}, recordedMessages(recorder.take("d")))
+	assert.Equal(t, uint64(3), recorder.evicted.Load())
+
+	assert.True(t, recorder.records(slog.LevelDebug))
+	assert.True(t, recorder.records(slog.LevelWarn))
+	assert.False(t, recorder.records(slog.LevelError))
+	assert.True(t, recorder.triggers(slog.LevelError))
+
+	// disabled
+	recorder = newFlightRecorder(&FlightRecorderConfig{Size: 3}, newLevelNames(nil))
+	assert.False(t, recorder.records(slog.LevelDebug))
+	assert.False(t, recorder.triggers(slog.LevelError))
+}
+
+func TestMangoLogger_FlightRecorder(t *testing.T) {
+	out := &OutConfig{Enabled: true, FlightRecorder: &FlightRecorderConfig{Enabled: true, Size: 2}}
+	handler, buf := newBufferedLogger(t, &LogConfig{Out: out}, slog.LevelInfo, nil)
+	logger := slog.New(handler)
+
+	checkout := WithCorrelationID(context.Background(), "checkout-1")
+	other := WithCorrelationID(context.Background(), "other")
+	logger.DebugContext(checkout, "loading cart")
+	logger.InfoContext(checkout, "checkout started")
+	logger.DebugContext(checkout, "cart loaded", "items", 3)
+	logger.DebugContext(other, "unrelated")
+	logger.DebugContext(checkout, "charging card")
+	logger.Debug("without correlation id")
+	assert.False(t, handler.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, handler.Enabled(checkout, slog.LevelDebug))
+
+	logger.ErrorContext(checkout, "payment failed")
+	logger.ErrorContext(checkout, "checkout failed")
+
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var log map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &log))
+		messages = append(messages, log["message"].(string))
+		if log["message"] == "cart loaded" {
+			assert.Equal(t, "DEBUG", log["level"])
+			assert.Equal(t, "checkout-1", log["correlationid"])
+			assert.Equal(t, map[string]interface{}{"items": float64(3)}, log["attributes"])
+		}
+	}
+	// the first debug record was overwritten, the other correlation id is not flushed
+	assert.Equal(t, []string{"checkout started", "cart loaded", "charging card", "payment failed", "checkout failed"}, messages)
+	assert.Equal(t, uint64(1), handler.Evicted())
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+var errNoGoJQResult = errors.New("jq format produced no result")
+
+// compileGoJQ parses and compiles the jq query, done once per format when the logger is created
+func compileGoJQ(query string) (*gojq.Code, error) {
+	parsed, err := gojq.Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return gojq.Compile(parsed)
+}
+
+// formatWithGoJQ runs the compiled format against the log, the last result being rendered as json
+// A string result is printed raw, as jq -r does, so templates don't come out quoted
+func formatWithGoJQ(code *gojq.Code, log *StructuredLog) ([]byte, error) {
+	iter := code.Run(log.toJQInput())
+
+	var result any
+	found := false
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		result = v
+		found = true
+	}
+	if !found {
+		return nil, errNoGoJQResult
+	}
+	if s, ok := result.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(result)
+}
+
+// toJQInput is the StructuredLog as the generic map gojq works on, using the json field names
+func (l *StructuredLog) toJQInput() map[string]any {
+	input := map[string]any{"attributes": toJQValue(l.Attributes)}
+	for _, field := range l.fields() {
+		if field.omitted() {
+			continue
+		}
+		input[field.name] = toJQValue(field.value)
+	}
+	return input
+}
+
+// toJQValue converts a value to the types supported by gojq (nil, bool, int, float64, string, []any and map[string]any)
+// Values without a direct equivalent are converted the way encoding/json renders them
+func toJQValue(v any) any {
+	switch value := v.(type) {
+	case nil, bool, int, float64, string:
+		return value
+	case *OrderedMap:
+		return toJQValue(value.Map())
+	case map[string]any:
+		converted := make(map[string]any, len(value))
+		for k, item := range value {
+			converted[k] = toJQValue(item)
+		}
+		return converted
+	case []any:
+		converted := make([]any, len(value))
+		for i, item := range value {
+			converted[i] = toJQValue(item)
+		}
+		return converted
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case float32:
+		return float64(value)
+	}
+
+	switch v.(type) {
+	case json.Marshaler, encoding.TextMarshaler:
+		return toJQValueFromJSON(v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt {
+			return float64(rv.Uint())
+		}
+		return int(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+
+	return toJQValueFromJSON(v)
+}
+
+// toJQValueFromJSON converts the value through its json representation
+func toJQValueFromJSON(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return fmt.Sprint(v)
+	}
+	return generic
+}
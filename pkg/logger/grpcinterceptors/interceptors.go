@@ -0,0 +1,255 @@
+// Package grpcinterceptors provides gRPC client and server interceptors propagating the mango logging context
+// and logging the outcome of every call with a logger.MangoLogger.
+// It is kept apart from the logger package so services without gRPC don't depend on it.
+package grpcinterceptors
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bitstep-ie/mango-go/pkg/logger"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultCorrelationMetadataKey is the metadata key carrying the correlation id when Config.CorrelationMetadataKey is not set
+const DefaultCorrelationMetadataKey = "x-correlation-id"
+
+// traceparentMetadataKey is the W3C trace context metadata copied into logger.TRACEPARENT on the server side
+const traceparentMetadataKey = "traceparent"
+
+// Config configures the interceptors
+type Config struct {
+	// Application set as logger.APPLICATION in the call context, when not empty
+	Application string
+
+	// Type set as logger.TYPE in the call context - Defaults to logger.BusinessType
+	// The call outcome is always logged as a logger.PerformanceType entry
+	Type string
+
+	// CorrelationMetadataKey carries the correlation id in the gRPC metadata - Defaults to DefaultCorrelationMetadataKey
+	// Servers echo it in the response header, and a new correlation id is generated when the call has none
+	CorrelationMetadataKey string
+
+	// DisableCallLog switches off the log of the call outcome
+	DisableCallLog bool
+}
+
+// interceptors holds the defaulted Config shared by the client and server interceptors
+type interceptors struct {
+	config Config
+	log    *slog.Logger
+}
+
+func newInterceptors(mangoLogger *logger.MangoLogger, config Config) *interceptors {
+	if config.CorrelationMetadataKey == "" {
+		config.CorrelationMetadataKey = DefaultCorrelationMetadataKey
+	}
+	if config.Type == "" {
+		config.Type = logger.BusinessType
+	}
+	return &interceptors{config: config, log: slog.New(mangoLogger)}
+}
+
+// UnaryServerInterceptor populates the logging context of unary calls from the incoming metadata and logs their outcome
+func UnaryServerInterceptor(mangoLogger *logger.MangoLogger, config Config) grpc.UnaryServerInterceptor {
+	i := newInterceptors(mangoLogger, config)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx = i.serverContext(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		i.logCall(ctx, "server", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor populates the logging context of streaming calls from the incoming metadata and logs their outcome
+func StreamServerInterceptor(mangoLogger *logger.MangoLogger, config Config) grpc.StreamServerInterceptor {
+	i := newInterceptors(mangoLogger, config)
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := i.serverContext(stream.Context(), info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+		i.logCall(ctx, "server", info.FullMethod, start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor sends the correlation id of the context in the outgoing metadata and logs the outcome of unary calls
+func UnaryClientInterceptor(mangoLogger *logger.MangoLogger, config Config) grpc.UnaryClientInterceptor {
+	i := newInterceptors(mangoLogger, config)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx = i.clientContext(ctx, method)
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		i.logCall(ctx, "client", method, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor sends the correlation id of the context in the outgoing metadata and logs the outcome of streaming calls,
+// once the stream fails to open or ends: RecvMsg returning an error (io.EOF being a success), the response of a client-streaming call
+// being received, SendMsg or CloseSend failing, or the context of the call being done for streams the caller abandons.
+// No goroutine is started per stream, but as for gRPC itself a stream neither read to its end nor canceled is never released:
+// callers abandoning a stream must cancel its context.
+func StreamClientInterceptor(mangoLogger *logger.MangoLogger, config Config) grpc.StreamClientInterceptor {
+	i := newInterceptors(mangoLogger, config)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx = i.clientContext(ctx, method)
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			i.logCall(ctx, "client", method, start, err)
+			return nil, err
+		}
+		cs := &clientStream{ClientStream: stream, serverStreams: desc.ServerStreams, done: func(err error) {
+			i.logCall(ctx, "client", method, start, err)
+		}}
+		// the stream ends when the context of the call is done first, e.g. canceled by a caller abandoning it
+		cs.stopWatch = context.AfterFunc(ctx, func() {
+			cs.finish(status.FromContextError(ctx.Err()).Err())
+		})
+		return cs, nil
+	}
+}
+
+// serverContext adds the mango contract fields to the context of the call and echoes the correlation id in the response header
+func (i *interceptors) serverContext(ctx context.Context, method string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	correlationID := firstValue(md, i.config.CorrelationMetadataKey)
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(i.config.CorrelationMetadataKey, correlationID))
+
+	ctx = i.withContractFields(ctx, correlationID, method)
+	if traceparent := firstValue(md, traceparentMetadataKey); traceparent != "" {
+		ctx = context.WithValue(ctx, logger.TRACEPARENT, traceparent)
+	}
+	return ctx
+}
+
+// clientContext adds the mango contract fields to the context of the call and the correlation id to the outgoing metadata
+// The correlation id of the context is reused, or generated when missing
+func (i *interceptors) clientContext(ctx context.Context, method string) context.Context {
+	correlationID, _ := logger.CorrelationIDFrom(ctx)
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, i.config.CorrelationMetadataKey, correlationID)
+	return i.withContractFields(ctx, correlationID, method)
+}
+
+func (i *interceptors) withContractFields(ctx context.Context, correlationID, method string) context.Context {
+	ctx = logger.WithLogFields(ctx, logger.LogFields{CorrelationID: correlationID, Operation: method})
+	if i.config.Application != "" {
+		ctx = logger.WithApplication(ctx, i.config.Application)
+	}
+	if _, ok := logger.TypeFrom(ctx); !ok {
+		ctx = logger.WithType(ctx, i.config.Type)
+	}
+	return ctx
+}
+
+// logCall logs the outcome of the call as a PerformanceType entry, at a level depending on its status code
+func (i *interceptors) logCall(ctx context.Context, kind, method string, start time.Time, err error) {
+	if i.config.DisableCallLog {
+		return
+	}
+	code := status.Code(err)
+	i.log.LogAttrs(logger.WithType(ctx, logger.PerformanceType), codeLevel(code), "grpc call",
+		slog.String("kind", kind),
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// codeLevel is INFO for OK, WARN for codes caused by the caller and ERROR for the server side failures
+func codeLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.ResourceExhausted, codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// serverStream overrides the context of the stream with the one holding the logging fields
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// clientStream calls done once, when the stream ends
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	once          sync.Once
+	stopWatch     func() bool
+	done          func(err error)
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	// io.EOF means the server ended the stream, its status being returned by RecvMsg
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.end(err)
+	}
+	return err
+}
+
+func (s *clientStream) CloseSend() error {
+	err := s.ClientStream.CloseSend()
+	if err != nil {
+		s.end(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	case !s.serverStreams:
+		// the single response of a client-streaming call ends it
+		s.end(nil)
+	}
+	return err
+}
+
+// end finishes the stream from a call of the caller, no longer watching its context
+func (s *clientStream) end(err error) {
+	s.stopWatch()
+	s.finish(err)
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() {
+		s.done(err)
+	})
+}
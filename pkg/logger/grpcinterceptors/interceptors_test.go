@@ -0,0 +1,330 @@
+package grpcinterceptors
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitstep-ie/mango-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// syncBuffer is a bytes.Buffer safe to read while the async worker writes to it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func newTestLogger(t *testing.T) (*logger.MangoLogger, *syncBuffer) {
+	mangoLogger, err := logger.New(&logger.LogConfig{
+		MangoConfig: &logger.MangoConfig{Strict: true},
+		Out:         &logger.OutConfig{Enabled: true, Async: &logger.AsyncConfig{Enabled: true}},
+	})
+	assert.NoError(t, err)
+	var buf syncBuffer
+	mangoLogger.AddAppender(logger.NewWriterAppender(&buf, slog.LevelInfo, nil))
+	t.Cleanup(func() { _ = mangoLogger.Close(context.Background()) })
+	return mangoLogger, &buf
+}
+
+// checkingHealthServer records the logging context of the calls it serves
+type checkingHealthServer struct {
+	*health.Server
+	contexts chan context.Context
+}
+
+func (s *checkingHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s.contexts <- ctx
+	return s.Server.Check(ctx, req)
+}
+
+func (s *checkingHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	s.contexts <- stream.Context()
+	return status.Error(codes.Unavailable, "going away")
+}
+
+// uploadDesc is a client-streaming method taking health check requests and answering once the client is done sending
+var uploadDesc = grpc.StreamDesc{StreamName: "Upload", ClientStreams: true}
+
+var uploadService = grpc.ServiceDesc{
+	ServiceName: "test.Uploader",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    uploadDesc.StreamName,
+		ClientStreams: true,
+		Handler: func(_ any, stream grpc.ServerStream) error {
+			for {
+				if err := stream.RecvMsg(&healthpb.HealthCheckRequest{}); err != nil {
+					if errors.Is(err, io.EOF) {
+						return stream.SendMsg(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+					}
+					return err
+				}
+			}
+		},
+	}},
+}
+
+const uploadMethod = "/test.Uploader/Upload"
+
+func startServer(t *testing.T, serverLogger, clientLogger *logger.MangoLogger) (*grpc.ClientConn, chan context.Context) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(serverLogger, Config{Application: "server-app"})),
+		grpc.StreamInterceptor(StreamServerInterceptor(serverLogger, Config{Application: "server-app"})),
+	)
+	healthServer := &checkingHealthServer{Server: health.NewServer(), contexts: make(chan context.Context, 2)}
+	healthpb.RegisterHealthServer(server, healthServer)
+	server.RegisterService(&uploadService, struct{}{})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(clientLogger, Config{Application: "client-app"})),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(clientLogger, Config{Application: "client-app"})),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, healthServer.contexts
+}
+
+func decodeLogs(t *testing.T, mangoLogger *logger.MangoLogger, buf *syncBuffer) []map[string]any {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, mangoLogger.Flush(ctx))
+
+	var logs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var log map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &log))
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	serverLogger, serverBuf := newTestLogger(t)
+	clientLogger, clientBuf := newTestLogger(t)
+	conn, contexts := startServer(t, serverLogger, clientLogger)
+	client := healthpb.NewHealthClient(conn)
+
+	ctx := context.WithValue(context.Background(), logger.CORRELATION_ID, "corr-1")
+	ctx = metadata.AppendToOutgoingContext(ctx, traceparentMetadataKey, testTraceparent)
+	var header metadata.MD
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"corr-1"}, header.Get(DefaultCorrelationMetadataKey))
+
+	serverCtx := <-contexts
+	assert.Equal(t, "corr-1", serverCtx.Value(logger.CORRELATION_ID))
+	assert.Equal(t, "/grpc.health.v1.Health/Check", serverCtx.Value(logger.OPERATION))
+	assert.Equal(t, "server-app", serverCtx.Value(logger.APPLICATION))
+	assert.Equal(t, logger.BusinessType, serverCtx.Value(logger.TYPE))
+
+	for _, logs := range [][]map[string]any{decodeLogs(t, serverLogger, serverBuf), decodeLogs(t, clientLogger, clientBuf)} {
+		assert.Len(t, logs, 1)
+		assert.Equal(t, "corr-1", logs[0]["correlationid"])
+		assert.Equal(t, logger.PerformanceType, logs[0]["type"])
+		assert.Equal(t, "/grpc.health.v1.Health/Check", logs[0]["operation"])
+		assert.Equal(t, "INFO", logs[0]["level"])
+		attributes := logs[0]["attributes"].(map[string]any)
+		assert.Equal(t, "OK", attributes["code"])
+		assert.Contains(t, attributes, "duration")
+	}
+	serverLog := decodeLogs(t, serverLogger, serverBuf)[0]
+	assert.Equal(t, "server", serverLog["attributes"].(map[string]any)["kind"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serverLog["traceId"])
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	serverLogger, serverBuf := newTestLogger(t)
+	clientLogger, clientBuf := newTestLogger(t)
+	conn, contexts := startServer(t, serverLogger, clientLogger)
+	client := healthpb.NewHealthClient(conn)
+
+	// no correlation id in context, the client generates one
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	serverCtx := <-contexts
+	correlationID, _ := serverCtx.Value(logger.CORRELATION_ID).(string)
+	assert.NotEmpty(t, correlationID)
+	assert.Equal(t, "/grpc.health.v1.Health/Watch", serverCtx.Value(logger.OPERATION))
+
+	for _, logs := range [][]map[string]any{decodeLogs(t, serverLogger, serverBuf), decodeLogs(t, clientLogger, clientBuf)} {
+		assert.Len(t, logs, 1)
+		assert.Equal(t, correlationID, logs[0]["correlationid"])
+		assert.Equal(t, "ERROR", logs[0]["level"])
+		assert.Equal(t, "Unavailable", logs[0]["attributes"].(map[string]any)["code"])
+	}
+}
+
+func TestClientStreamingInterceptors(t *testing.T) {
+	serverLogger, serverBuf := newTestLogger(t)
+	clientLogger, clientBuf := newTestLogger(t)
+	conn, _ := startServer(t, serverLogger, clientLogger)
+
+	ctx := context.WithValue(context.Background(), logger.CORRELATION_ID, "upload-1")
+	stream, err := conn.NewStream(ctx, &uploadDesc, uploadMethod)
+	assert.NoError(t, err)
+	for range 3 {
+		assert.NoError(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	}
+	assert.NoError(t, stream.CloseSend())
+	var resp healthpb.HealthCheckResponse
+	assert.NoError(t, stream.RecvMsg(&resp))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// one log per call, on both sides
+	for _, logs := range [][]map[string]any{decodeLogs(t, serverLogger, serverBuf), decodeLogs(t, clientLogger, clientBuf)} {
+		assert.Len(t, logs, 1)
+		assert.Equal(t, "upload-1", logs[0]["correlationid"])
+		assert.Equal(t, uploadMethod, logs[0]["operation"])
+		assert.Equal(t, "OK", logs[0]["attributes"].(map[string]any)["code"])
+	}
+
+	// a stream abandoned by the caller is logged once its context is canceled
+	clientBuf.Reset()
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), logger.CORRELATION_ID, "upload-2"))
+	stream, err = conn.NewStream(ctx, &uploadDesc, uploadMethod)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	cancel()
+	assert.Eventually(t, func() bool {
+		return strings.Contains(clientBuf.String(), "upload-2")
+	}, time.Second, 10*time.Millisecond)
+	logs := decodeLogs(t, clientLogger, clientBuf)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "WARN", logs[0]["level"])
+	assert.Equal(t, "Canceled", logs[0]["attributes"].(map[string]any)["code"])
+}
+
+// idleClientStream is a stream the caller never reads, failing SendMsg with sendErr
+type idleClientStream struct {
+	grpc.ClientStream
+	sendErr error
+}
+
+func (s *idleClientStream) SendMsg(any) error {
+	return s.sendErr
+}
+
+func idleStreamer(sendErr error) grpc.Streamer {
+	return func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &idleClientStream{sendErr: sendErr}, nil
+	}
+}
+
+func TestStreamClientInterceptor_NoGoroutinePerStream(t *testing.T) {
+	clientLogger, clientBuf := newTestLogger(t)
+	interceptor := StreamClientInterceptor(clientLogger, Config{Application: "client-app"})
+
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), logger.CORRELATION_ID, "watch-1"))
+	for range 100 {
+		_, err := interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/test.Service/Watch", idleStreamer(nil))
+		assert.NoError(t, err)
+	}
+	// streams neither read nor canceled don't hold a goroutine each
+	assert.Less(t, runtime.NumGoroutine()-before, 10)
+	assert.Empty(t, clientBuf.String())
+
+	// and are all logged once canceled
+	cancel()
+	assert.Eventually(t, func() bool {
+		return strings.Count(clientBuf.String(), "\n") == 100
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine()-before < 10
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStreamClientInterceptor_SendFailure(t *testing.T) {
+	clientLogger, clientBuf := newTestLogger(t)
+	interceptor := StreamClientInterceptor(clientLogger, Config{Application: "client-app"})
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), logger.CORRELATION_ID, "send-1"))
+	stream, err := interceptor(ctx, &grpc.StreamDesc{ClientStreams: true}, nil, uploadMethod, idleStreamer(status.Error(codes.Unavailable, "gone")))
+	assert.NoError(t, err)
+	assert.Error(t, stream.SendMsg(&healthpb.HealthCheckRequest{}))
+	// the canceled context no longer logs the ended stream
+	cancel()
+
+	logs := decodeLogs(t, clientLogger, clientBuf)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "Unavailable", logs[0]["attributes"].(map[string]any)["code"])
+}
+
+func TestInterceptors_WithoutApplication(t *testing.T) {
+	mangoLogger, err := logger.New(&logger.LogConfig{Out: &logger.OutConfig{Enabled: true}})
+	assert.NoError(t, err)
+	var buf syncBuffer
+	mangoLogger.AddAppender(logger.NewWriterAppender(&buf, slog.LevelInfo, nil))
+	t.Cleanup(func() { _ = mangoLogger.Close(context.Background()) })
+
+	var application string
+	var set bool
+	invoker := func(ctx context.Context, _ string, _, _ any, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		application, set = logger.ApplicationFrom(ctx)
+		return nil
+	}
+	interceptor := UnaryClientInterceptor(mangoLogger, Config{})
+
+	// no empty application in the call context, the logger falling back to its own
+	assert.NoError(t, interceptor(context.Background(), "/test.Service/Get", nil, nil, nil, invoker))
+	assert.False(t, set)
+	assert.Contains(t, buf.String(), `"application":"unknownApplication"`)
+
+	// the application of the caller is kept
+	assert.NoError(t, interceptor(logger.WithApplication(context.Background(), "gateway"), "/test.Service/Get", nil, nil, nil, invoker))
+	assert.Equal(t, "gateway", application)
+}
+
+func TestCodeLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelInfo, codeLevel(codes.OK))
+	assert.Equal(t, slog.LevelWarn, codeLevel(codes.NotFound))
+	assert.Equal(t, slog.LevelWarn, codeLevel(codes.Canceled))
+	assert.Equal(t, slog.LevelError, codeLevel(codes.Internal))
+	assert.Equal(t, slog.LevelError, codeLevel(codes.DeadlineExceeded))
+}
@@ -0,0 +1,189 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultCorrelationHeader is the header carrying the correlation id when HTTPMiddlewareConfig.CorrelationHeader is not set
+const DefaultCorrelationHeader = "X-Correlation-ID"
+
+// maxCorrelationIDLength is the longest correlation id accepted from a request header
+const maxCorrelationIDLength = 128
+
+// traceparentHeader is the W3C trace context header copied into TRACEPARENT
+const traceparentHeader = "traceparent"
+
+// HTTPMiddlewareConfig configures the middleware created by NewHTTPMiddleware
+type HTTPMiddlewareConfig struct {
+	// Application set as APPLICATION in the request context, when not empty
+	Application string
+
+	// Type set as TYPE in the request context - Defaults to BusinessType
+	// The access log is always a PerformanceType entry
+	Type string
+
+	// CorrelationHeader is read for the correlation id and echoed in the response - Defaults to DefaultCorrelationHeader
+	// A new correlation id is generated when the request has none, or one longer than 128 characters or with characters
+	// other than letters, digits, '-', '_', '.' and ':'
+	CorrelationHeader string
+
+	// Operation derives OPERATION from the request - Defaults to the route of the wrapped http.ServeMux (see ServeMuxOperation),
+	// or the matched route (http.Request.Pattern) when the middleware wraps a single handler, "METHOD unmatched" otherwise
+	Operation func(r *http.Request) string
+
+	// DisableAccessLog switches off the access log written once the request is served
+	DisableAccessLog bool
+}
+
+// ServeMuxOperation derives the operation from the pattern of mux matching the request, "METHOD unmatched" when none matches
+func ServeMuxOperation(mux *http.ServeMux) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			return pattern
+		}
+		return unmatchedOperation(r)
+	}
+}
+
+// defaultOperation is the route matched by the http.ServeMux serving the request, "METHOD unmatched" otherwise
+func defaultOperation(r *http.Request) string {
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	return unmatchedOperation(r)
+}
+
+// unmatchedOperation is the operation of a request without route, never the raw path to keep the operations bounded
+func unmatchedOperation(r *http.Request) string {
+	return r.Method + " unmatched"
+}
+
+// validCorrelationID reports whether the correlation id of a request can be logged and echoed as is
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewHTTPMiddleware creates a net/http middleware populating the request context with the mango contract fields
+// (CORRELATION_ID, OPERATION, APPLICATION, TYPE and TRACEPARENT when the request has a traceparent header)
+// and writing a PerformanceType access log with the status, bytes written and latency of every request.
+// 5xx responses are logged at ERROR, 4xx at WARN and everything else at INFO.
+func NewHTTPMiddleware(logger *MangoLogger, config HTTPMiddlewareConfig) func(http.Handler) http.Handler {
+	if config.CorrelationHeader == "" {
+		config.CorrelationHeader = DefaultCorrelationHeader
+	}
+	if config.Type == "" {
+		config.Type = BusinessType
+	}
+	accessLog := slog.New(logger)
+
+	return func(next http.Handler) http.Handler {
+		operation := config.Operation
+		if operation == nil {
+			operation = defaultOperation
+			if mux, ok := next.(*http.ServeMux); ok {
+				operation = ServeMuxOperation(mux)
+			}
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			correlationID := r.Header.Get(config.CorrelationHeader)
+			if !validCorrelationID(correlationID) {
+				correlationID = uuid.New().String()
+			}
+			w.Header().Set(config.CorrelationHeader, correlationID)
+
+			ctx := WithLogFields(r.Context(), LogFields{
+				CorrelationID: correlationID,
+				Operation:     operation(r),
+				Type:          config.Type,
+			})
+			if config.Application != "" {
+				ctx = WithApplication(ctx, config.Application)
+			}
+			if traceparent := r.Header.Get(traceparentHeader); traceparent != "" {
+				ctx = context.WithValue(ctx, TRACEPARENT, traceparent)
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			if config.DisableAccessLog {
+				return
+			}
+			status := recorder.statusCode()
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+			accessLog.LogAttrs(WithType(ctx, PerformanceType), level, "http request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", recorder.bytes),
+				slog.Duration("latency", time.Since(start)),
+			)
+		})
+	}
+}
+
+// statusRecorder captures the status code and the number of bytes of the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush supports streaming handlers asserting http.Flusher
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap gives http.ResponseController access to the Flush, Hijack and deadline methods of the wrapped writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// statusCode of the response, 200 when the handler wrote nothing
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,182 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitstep-ie/mango-go/pkg/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// strictConfig requires every context field, as the middleware sets them
+func strictConfig() *LogConfig {
+	return &LogConfig{MangoConfig: &MangoConfig{Strict: true, CorrelationId: &CorrelationIdConfig{Strict: true}}}
+}
+
+func decodeLogs(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var logs []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var log map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &log))
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	logger, buf := newBufferedLogger(t, strictConfig(), slog.LevelInfo, nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /carts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		slog.New(logger).InfoContext(r.Context(), "cart updated")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	})
+	handler := NewHTTPMiddleware(logger, HTTPMiddlewareConfig{Application: "checkout-api", Operation: ServeMuxOperation(mux)})(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/carts/42", nil)
+	req.Header.Set(DefaultCorrelationHeader, "corr-1")
+	req.Header.Set("traceparent", testTraceparent)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "corr-1", rec.Header().Get(DefaultCorrelationHeader))
+
+	logs := decodeLogs(t, buf)
+	assert.Len(t, logs, 2)
+	for _, log := range logs {
+		assert.Equal(t, "corr-1", log["correlationid"])
+		assert.Equal(t, "POST /carts/{id}", log["operation"])
+		assert.Equal(t, "checkout-api", log["application"])
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", log["traceId"])
+	}
+	assert.Equal(t, BusinessType, logs[0]["type"])
+
+	access := logs[1]
+	assert.Equal(t, PerformanceType, access["type"])
+	assert.Equal(t, "INFO", access["level"])
+	attributes := access["attributes"].(map[string]any)
+	assert.Equal(t, "POST", attributes["method"])
+	assert.Equal(t, "/carts/42", attributes["path"])
+	assert.Equal(t, float64(http.StatusCreated), attributes["status"])
+	assert.Equal(t, float64(len("created")), attributes["bytes"])
+	assert.Contains(t, attributes, "latency")
+}
+
+func TestHTTPMiddleware_GeneratedCorrelationAndStatusLevels(t *testing.T) {
+	logger, buf := newBufferedLogger(t, strictConfig(), slog.LevelInfo, nil)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /fail", NewHTTPMiddleware(logger, HTTPMiddlewareConfig{Application: "checkout-api", CorrelationHeader: "X-Request-ID"})(
+		http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "boom", http.StatusServiceUnavailable)
+		})))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	correlationID := rec.Header().Get("X-Request-ID")
+	assert.NotEmpty(t, correlationID)
+
+	logs := decodeLogs(t, buf)
+	assert.Len(t, logs, 1)
+	assert.Equal(t, "ERROR", logs[0]["level"])
+	assert.Equal(t, correlationID, logs[0]["correlationid"])
+	// the route of the wrapped handler
+	assert.Equal(t, "GET /fail", logs[0]["operation"])
+
+	buf.Reset()
+	rec = httptest.NewRecorder()
+	NewHTTPMiddleware(logger, HTTPMiddlewareConfig{Application: "checkout-api"})(http.NotFoundHandler()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	logs = decodeLogs(t, buf)
+	assert.Equal(t, "WARN", logs[0]["level"])
+	assert.Equal(t, "GET unmatched", logs[0]["operation"])
+}
+
+func TestHTTPMiddleware_ServeMuxOperation(t *testing.T) {
+	logger, buf := newBufferedLogger(t, strictConfig(), slog.LevelInfo, nil)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /carts/{id}", func(w http.ResponseWriter, _ *http.Request) {})
+	handler := NewHTTPMiddleware(logger, HTTPMiddlewareConfig{Application: "checkout-api"})(mux)
+
+	// the route of the wrapped mux, never the raw path
+	for _, path := range []string{"/carts/42", "/carts/43", "/admin/7f3a"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	logs := decodeLogs(t, buf)
+	assert.Len(t, logs, 3)
+	assert.Equal(t, "GET /carts/{id}", logs[0]["operation"])
+	assert.Equal(t, "GET /carts/{id}", logs[1]["operation"])
+	assert.Equal(t, "GET unmatched", logs[2]["operation"])
+}
+
+func TestHTTPMiddleware_InvalidCorrelationHeader(t *testing.T) {
+	logger, buf := newBufferedLogger(t, strictConfig(), slog.LevelInfo, nil)
+	handler := NewHTTPMiddleware(logger, HTTPMiddlewareConfig{Application: "checkout-api"})(http.NotFoundHandler())
+
+	valid := "abc-123_x.y:z"
+	for header, kept := range map[string]bool{
+		valid:                       true,
+		strings.Repeat("a", 128):    true,
+		strings.Repeat("a", 129):    false,
+		"forged level=ERROR":        false,
+		"<script>alert(1)</script>": false,
+		"café":                      false,
+	} {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(DefaultCorrelationHeader, header)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		correlationID := rec.Header().Get(DefaultCorrelationHeader)
+		assert.Equal(t, correlationID, decodeLogs(t, buf)[0]["correlationid"])
+		if kept {
+			assert.Equal(t, header, correlationID)
+		} else {
+			assert.NotEqual(t, header, correlationID)
+			testutils.AssertValidUUID(t, correlationID, "correlationid")
+		}
+	}
+}
+
+func TestHTTPMiddleware_DisableAccessLog(t *testing.T) {
+	logger, buf := newBufferedLogger(t, strictConfig(), slog.LevelInfo, nil)
+
+	handler := NewHTTPMiddleware(logger, HTTPMiddlewareConfig{DisableAccessLog: true})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.(http.Flusher).Flush()
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, rec.Flushed)
+	assert.Empty(t, buf.String())
+}
+
+func TestHTTPMiddleware_WithoutApplication(t *testing.T) {
+	logger, buf := newBufferedLogger(t, &LogConfig{}, slog.LevelInfo, nil)
+	handler := NewHTTPMiddleware(logger, HTTPMiddlewareConfig{})(http.NotFoundHandler())
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "unknownApplication", decodeLogs(t, buf)[0]["application"])
+
+	// the application of the request context is kept
+	buf.Reset()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(WithApplication(req.Context(), "gateway")))
+	assert.Equal(t, "gateway", decodeLogs(t, buf)[0]["application"])
+
+	// no empty application satisfying the strict contract
+	strictLogger, strictBuf := newBufferedLogger(t, strictConfig(), slog.LevelInfo, nil)
+	NewHTTPMiddleware(strictLogger, HTTPMiddlewareConfig{})(http.NotFoundHandler()).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, strictBuf.String())
+}
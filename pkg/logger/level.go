@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Suggested custom levels, name them through MangoConfig.LevelNames
+const (
+	LevelTrace  = slog.Level(-8)
+	LevelNotice = slog.Level(2)
+	LevelFatal  = slog.Level(12)
+)
+
+// levelNames resolves custom level names in both directions
+type levelNames struct {
+	byName  map[string]slog.Level
+	byLevel map[slog.Level]string
+}
+
+func newLevelNames(names map[string]int) levelNames {
+	ln := levelNames{byName: make(map[string]slog.Level), byLevel: make(map[slog.Level]string)}
+	for name, level := range names {
+		ln.byName[strings.ToUpper(name)] = slog.Level(level)
+		ln.byLevel[slog.Level(level)] = strings.ToUpper(name)
+	}
+	return ln
+}
+
+// name of the level, the custom one if configured or the slog one otherwise (e.g. INFO, DEBUG-4)
+func (ln levelNames) name(level slog.Level) string {
+	if name, ok := ln.byLevel[level]; ok {
+		return name
+	}
+	return level.String()
+}
+
+// width of the longest level name, custom ones included, to align them
+func (ln levelNames) width() int {
+	width := len("DEBUG")
+	for name := range ln.byName {
+		width = max(width, utf8.RuneCountInString(name))
+	}
+	return width
+}
+
+// parse a custom level name or any slog level text (e.g. WARN, debug-4, 12)
+func (ln levelNames) parse(name string) (slog.Level, error) {
+	if level, ok := ln.byName[strings.ToUpper(strings.TrimSpace(name))]; ok {
+		return level, nil
+	}
+	if number, err := strconv.Atoi(strings.TrimSpace(name)); err == nil {
+		return slog.Level(number), nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// newLevelVar holds the configured level name, or the fallback when not configured
+func (ln levelNames) newLevelVar(configured string, fallback slog.Level) (*slog.LevelVar, error) {
+	levelVar := &slog.LevelVar{}
+	levelVar.Set(fallback)
+	if configured == "" {
+		return levelVar, nil
+	}
+	level, err := ln.parse(configured)
+	if err != nil {
+		return levelVar, err
+	}
+	levelVar.Set(level)
+	return levelVar, nil
+}
+
+// outputLevel is the minimum level of a built-in output, following its configured level name (or debug flag) read live from the config
+// A level set at runtime through the LevelVar holds until the configuration changes
+type outputLevel struct {
+	output string
+	levels levelNames
+	config func() (name string, debug bool)
+
+	mu    sync.Mutex
+	seen  atomic.Pointer[outputLevelSetting]
+	level slog.LevelVar
+}
+
+// outputLevelSetting is the configuration the level was last set from
+type outputLevelSetting struct {
+	name  string
+	debug bool
+}
+
+// newOutputLevel sets the level from the current configuration, an invalid level name being reported by LogConfig.Validate
+func newOutputLevel(output string, levels levelNames, config func() (string, bool)) *outputLevel {
+	o := &outputLevel{output: output, levels: levels, config: config}
+	name, debug := config()
+	_ = o.set(outputLevelSetting{name: name, debug: debug})
+	return o
+}
+
+// Level is the configured level, or DEBUG when debug is allowed and INFO otherwise if not set or invalid
+func (o *outputLevel) Level() slog.Level {
+	o.sync()
+	return o.level.Level()
+}
+
+// Var is the LevelVar holding the level, to change it at runtime
+func (o *outputLevel) Var() *slog.LevelVar {
+	o.sync()
+	return &o.level
+}
+
+// sync sets the level again when the configuration changed since it was last set
+func (o *outputLevel) sync() {
+	name, debug := o.config()
+	if seen := o.seen.Load(); seen.name == name && seen.debug == debug {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if seen := o.seen.Load(); seen.name == name && seen.debug == debug {
+		return
+	}
+	if err := o.set(outputLevelSetting{name: name, debug: debug}); err != nil {
+		fmt.Printf("Invalid %s output level, using %s. %s\n", o.output, o.levels.name(o.level.Level()), err.Error())
+	}
+}
+
+func (o *outputLevel) set(setting outputLevelSetting) error {
+	fallback := slog.LevelInfo
+	if setting.debug {
+		fallback = slog.LevelDebug
+	}
+	levelVar, err := o.levels.newLevelVar(setting.name, fallback)
+	o.level.Set(levelVar.Level())
+	o.seen.Store(&setting)
+	return err
+}
+
+// operationLevels are the minimum levels set at runtime with SetOperationLevel, overriding the output levels
+type operationLevels struct {
+	mu     sync.RWMutex
+	levels map[string]slog.Level
+}
+
+func (ol *operationLevels) get(operation string) (slog.Level, bool) {
+	if ol == nil {
+		return 0, false
+	}
+	ol.mu.RLock()
+	defer ol.mu.RUnlock()
+	level, ok := ol.levels[operation]
+	return level, ok
+}
+
+func (ol *operationLevels) set(operation string, level slog.Level) {
+	ol.mu.Lock()
+	defer ol.mu.Unlock()
+	if ol.levels == nil {
+		ol.levels = make(map[string]slog.Level)
+	}
+	ol.levels[operation] = level
+}
+
+func (ol *operationLevels) reset(operation string) {
+	ol.mu.Lock()
+	defer ol.mu.Unlock()
+	delete(ol.levels, operation)
+}
+
+func (ol *operationLevels) all() map[string]slog.Level {
+	ol.mu.RLock()
+	defer ol.mu.RUnlock()
+	return maps.Clone(ol.levels)
+}
+
+// levelOverride replaces the minimum level of an appender
+type levelOverride struct {
+	Appender
+	level slog.Level
+}
+
+func (o levelOverride) Level() slog.Level {
+	return o.level
+}
@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LevelHandler is an http.Handler to read and change the levels of a running MangoLogger:
+//
+//	GET    /                      all the output and operation levels
+//	GET    /outputs/{output}      level of a built-in output (cli, file or syslog)
+//	PUT    /outputs/{output}      change it, body {"level": "DEBUG", "ttl": "15m"}
+//	GET    /operations/{operation} level override of an operation
+//	PUT    /operations/{operation} override it, body {"level": "DEBUG", "ttl": "15m"}
+//	DELETE /operations/{operation} remove the override
+//
+// The optional ttl reverts the change automatically once elapsed.
+// Mount it under a prefix with http.StripPrefix and protect it as any other admin endpoint.
+type LevelHandler struct {
+	logger  *MangoLogger
+	mux     *http.ServeMux
+	mu      sync.Mutex
+	reverts map[string]*levelRevert
+}
+
+// LevelState is the representation of a level in the LevelHandler responses
+type LevelState struct {
+	Level    string     `json:"level"`
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+}
+
+// LevelsResponse is the representation of all the levels in the LevelHandler responses
+type LevelsResponse struct {
+	Outputs    map[string]LevelState `json:"outputs"`
+	Operations map[string]LevelState `json:"operations"`
+}
+
+// LevelRequest is the body of a PUT to the LevelHandler
+type LevelRequest struct {
+	// Level is a slog level (INFO, debug-4, 12) or a name from MangoConfig.LevelNames
+	Level string `json:"level"`
+
+	// TTL is an optional time.Duration (e.g. "10m") after which the previous level is restored
+	TTL string `json:"ttl"`
+}
+
+// levelRevert restores the level in place before a temporary change
+type levelRevert struct {
+	timer   *time.Timer
+	at      time.Time
+	restore func()
+}
+
+var builtInOutputs = []string{OutputCli, OutputFile, OutputSyslog}
+
+// NewLevelHandler creates the admin handler for the logger levels
+func NewLevelHandler(logger *MangoLogger) *LevelHandler {
+	h := &LevelHandler{
+		logger:  logger,
+		mux:     http.NewServeMux(),
+		reverts: make(map[string]*levelRevert),
+	}
+	h.mux.HandleFunc("GET /{$}", h.getAll)
+	h.mux.HandleFunc("GET /outputs/{output}", h.getOutput)
+	h.mux.HandleFunc("PUT /outputs/{output}", h.putOutput)
+	h.mux.HandleFunc("GET /operations/{operation}", h.getOperation)
+	h.mux.HandleFunc("PUT /operations/{operation}", h.putOperation)
+	h.mux.HandleFunc("DELETE /operations/{operation}", h.deleteOperation)
+	return h
+}
+
+func (h *LevelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *LevelHandler) getAll(w http.ResponseWriter, _ *http.Request) {
+	response := LevelsResponse{Outputs: map[string]LevelState{}, Operations: map[string]LevelState{}}
+	for _, output := range builtInOutputs {
+		if levelVar := h.logger.OutputLevel(output); levelVar != nil {
+			response.Outputs[output] = h.state(outputKey(output), levelVar.Level())
+		}
+	}
+	for operation, level := range h.logger.OperationLevels() {
+		response.Operations[operation] = h.state(operationKey(operation), level)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *LevelHandler) getOutput(w http.ResponseWriter, r *http.Request) {
+	output := r.PathValue("output")
+	levelVar := h.logger.OutputLevel(output)
+	if levelVar == nil {
+		http.Error(w, "unknown output "+output, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.state(outputKey(output), levelVar.Level()))
+}
+
+func (h *LevelHandler) putOutput(w http.ResponseWriter, r *http.Request) {
+	output := r.PathValue("output")
+	levelVar := h.logger.OutputLevel(output)
+	if levelVar == nil {
+		http.Error(w, "unknown output "+output, http.StatusNotFound)
+		return
+	}
+	level, ttl, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	previous := levelVar.Level()
+	h.change(outputKey(output), ttl, func() { levelVar.Set(level) }, func() { levelVar.Set(previous) })
+	writeJSON(w, http.StatusOK, h.state(outputKey(output), levelVar.Level()))
+}
+
+func (h *LevelHandler) getOperation(w http.ResponseWriter, r *http.Request) {
+	operation := r.PathValue("operation")
+	level, ok := h.logger.OperationLevel(operation)
+	if !ok {
+		http.Error(w, "no level override for operation "+operation, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.state(operationKey(operation), level))
+}
+
+func (h *LevelHandler) putOperation(w http.ResponseWriter, r *http.Request) {
+	operation := r.PathValue("operation")
+	level, ttl, ok := h.decode(w, r)
+	if !ok {
+		return
+	}
+
+	restore := func() { h.logger.ResetOperationLevel(operation) }
+	if previous, exists := h.logger.OperationLevel(operation); exists {
+		restore = func() { h.logger.SetOperationLevel(operation, previous) }
+	}
+	h.change(operationKey(operation), ttl, func() { h.logger.SetOperationLevel(operation, level) }, restore)
+	writeJSON(w, http.StatusOK, h.state(operationKey(operation), level))
+}
+
+func (h *LevelHandler) deleteOperation(w http.ResponseWriter, r *http.Request) {
+	operation := r.PathValue("operation")
+	h.change(operationKey(operation), 0, func() { h.logger.ResetOperationLevel(operation) }, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decode the LevelRequest body, writing a bad request response when invalid
+func (h *LevelHandler) decode(w http.ResponseWriter, r *http.Request) (slog.Level, time.Duration, bool) {
+	var request LevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return 0, 0, false
+	}
+	level, err := h.logger.levels.parse(request.Level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return 0, 0, false
+	}
+	var ttl time.Duration
+	if request.TTL != "" {
+		ttl, err = time.ParseDuration(request.TTL)
+		if err != nil || ttl <= 0 {
+			http.Error(w, "invalid ttl "+request.TTL, http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+	return level, ttl, true
+}
+
+// change applies a level change, scheduling restore after ttl (if positive).
+// A pending revert is cancelled by any later change, a later temporary change keeps restoring the original level.
+func (h *LevelHandler) change(key string, ttl time.Duration, apply func(), restore func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if pending, ok := h.reverts[key]; ok {
+		pending.timer.Stop()
+		delete(h.reverts, key)
+		restore = pending.restore
+	}
+	apply()
+	if ttl <= 0 {
+		return
+	}
+
+	revert := &levelRevert{at: time.Now().Add(ttl), restore: restore}
+	revert.timer = time.AfterFunc(ttl, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.reverts[key] == revert {
+			revert.restore()
+			delete(h.reverts, key)
+		}
+	})
+	h.reverts[key] = revert
+}
+
+func (h *LevelHandler) state(key string, level slog.Level) LevelState {
+	state := LevelState{Level: h.logger.levels.name(level)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if revert, ok := h.reverts[key]; ok {
+		at := revert.at
+		state.RevertAt = &at
+	}
+	return state
+}
+
+func outputKey(output string) string {
+	return "output/" + output
+}
+
+func operationKey(operation string) string {
+	return "operation/" + operation
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,120 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func doLevelRequest(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestLevelHandler_Outputs(t *testing.T) {
+	logger := newTestLogger(true, true, false, true)
+	handler := NewLevelHandler(logger)
+
+	rec := doLevelRequest(t, handler, http.MethodGet, "/", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var all LevelsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &all))
+	assert.Equal(t, LevelsResponse{
+		Outputs: map[string]LevelState{
+			OutputCli:    {Level: "DEBUG"},
+			OutputFile:   {Level: "INFO"},
+			OutputSyslog: {Level: "DEBUG"},
+		},
+		Operations: map[string]LevelState{},
+	}, all)
+
+	rec = doLevelRequest(t, handler, http.MethodPut, "/outputs/file", `{"level":"debug"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"DEBUG"}`, rec.Body.String())
+	assert.Equal(t, slog.LevelDebug, logger.OutputLevel(OutputFile).Level())
+
+	rec = doLevelRequest(t, handler, http.MethodGet, "/outputs/file", "")
+	assert.JSONEq(t, `{"level":"DEBUG"}`, rec.Body.String())
+}
+
+func TestLevelHandler_Errors(t *testing.T) {
+	handler := NewLevelHandler(newTestLogger(true, true, false, true))
+
+	assert.Equal(t, http.StatusNotFound, doLevelRequest(t, handler, http.MethodGet, "/outputs/kafka", "").Code)
+	assert.Equal(t, http.StatusNotFound, doLevelRequest(t, handler, http.MethodPut, "/outputs/kafka", `{"level":"INFO"}`).Code)
+	assert.Equal(t, http.StatusNotFound, doLevelRequest(t, handler, http.MethodGet, "/operations/checkout", "").Code)
+	assert.Equal(t, http.StatusBadRequest, doLevelRequest(t, handler, http.MethodPut, "/outputs/cli", `{`).Code)
+	assert.Equal(t, http.StatusBadRequest, doLevelRequest(t, handler, http.MethodPut, "/outputs/cli", `{"level":"loud"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, doLevelRequest(t, handler, http.MethodPut, "/outputs/cli", `{"level":"INFO","ttl":"soon"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, doLevelRequest(t, handler, http.MethodPut, "/outputs/cli", `{"level":"INFO","ttl":"-1m"}`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, doLevelRequest(t, handler, http.MethodPost, "/outputs/cli", `{"level":"INFO"}`).Code)
+}
+
+func TestLevelHandler_Operations(t *testing.T) {
+	logger := newTestLogger(false, true, false, true)
+	logger.Config.MangoConfig.LevelNames = map[string]int{"TRACE": -8}
+	logger = NewMangoLogger(logger.Config)
+	handler := NewLevelHandler(logger)
+
+	ctx := context.WithValue(context.Background(), OPERATION, "checkout")
+	assert.False(t, logger.Enabled(ctx, slog.LevelDebug))
+
+	rec := doLevelRequest(t, handler, http.MethodPut, "/operations/checkout", `{"level":"TRACE"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"TRACE"}`, rec.Body.String())
+	assert.True(t, logger.Enabled(ctx, LevelTrace))
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug))
+
+	rec = doLevelRequest(t, handler, http.MethodGet, "/", "")
+	assert.Contains(t, rec.Body.String(), `"operations":{"checkout":{"level":"TRACE"}}`)
+
+	// the override applies to the records of the operation
+	var buf strings.Builder
+	logger.AddAppender(NewWriterAppender(&buf, slog.LevelInfo, FormatterFunc(func(log *StructuredLog) ([]byte, error) {
+		return []byte(log.Message.(string)), nil
+	})))
+	l := slog.New(logger)
+	l.DebugContext(ctx, "checkout debug")
+	l.DebugContext(context.Background(), "other debug")
+	assert.Equal(t, "checkout debug\n", buf.String())
+
+	assert.Equal(t, http.StatusNoContent, doLevelRequest(t, handler, http.MethodDelete, "/operations/checkout", "").Code)
+	_, ok := logger.OperationLevel("checkout")
+	assert.False(t, ok)
+}
+
+func TestLevelHandler_TTL(t *testing.T) {
+	logger := newTestLogger(true, true, false, true)
+	handler := NewLevelHandler(logger)
+
+	rec := doLevelRequest(t, handler, http.MethodPut, "/outputs/file", `{"level":"DEBUG","ttl":"1h"}`)
+	var state LevelState
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	assert.NotNil(t, state.RevertAt)
+
+	// a second temporary change keeps the original level to revert to
+	doLevelRequest(t, handler, http.MethodPut, "/outputs/file", `{"level":"DEBUG-4","ttl":"20ms"}`)
+	doLevelRequest(t, handler, http.MethodPut, "/operations/checkout", `{"level":"DEBUG","ttl":"20ms"}`)
+	assert.Equal(t, slog.LevelDebug-4, logger.OutputLevel(OutputFile).Level())
+
+	assert.Eventually(t, func() bool {
+		_, overridden := logger.OperationLevel("checkout")
+		return logger.OutputLevel(OutputFile).Level() == slog.LevelInfo && !overridden
+	}, time.Second, 5*time.Millisecond)
+
+	// a permanent change cancels the pending revert
+	doLevelRequest(t, handler, http.MethodPut, "/outputs/cli", `{"level":"WARN","ttl":"20ms"}`)
+	rec = doLevelRequest(t, handler, http.MethodPut, "/outputs/cli", `{"level":"ERROR"}`)
+	assert.JSONEq(t, `{"level":"ERROR"}`, rec.Body.String())
+	time.Sleep(40 * time.Millisecond)
+	assert.Equal(t, slog.LevelError, logger.OutputLevel(OutputCli).Level())
+}
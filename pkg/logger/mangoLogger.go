@@ -2,65 +2,208 @@ package logger
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/itchyny/gojq"
 	"github.com/natefinch/lumberjack"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"slices"
-	"unsafe"
+	"strconv"
+	"time"
 )
 
 type MangoLogger struct {
 	attrs     []slog.Attr
+	groups    []string
+	appenders []Appender
+	async     *asyncQueue
+	sampler   *sampler
+	recorder  *flightRecorder
+	levels    levelNames
+	opLevels  *operationLevels
+	contract  *contextContract
+	redactor  *redactor
 	Config    *LogConfig
 	LogWriter *lumberjack.Logger
 }
 
+// Names of the built-in outputs
+const (
+	OutputCli    = "cli"
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+)
+
 var errStrictModeOn = fmt.Errorf("[STRICT_MODE ON] without required context fields %v", REQUIRED_FIELDS)
 
+// New creates a MangoLogger from the configuration.
+// Nil sub-configs are filled with disabled defaults (see LogConfig.SetDefaults) and the configuration is validated,
+// an invalid one is rejected with the ConfigError(s) found, matching ErrInvalidConfig.
+func New(config *LogConfig) (*MangoLogger, error) {
+	logger, err := newMangoLogger(config)
+	if err != nil {
+		return nil, err
+	}
+	return logger, nil
+}
+
+// NewMangoLogger creates a MangoLogger like New, without failing on an invalid configuration.
+// The problems found are printed and the logger is created regardless, a nil configuration gives a logger with every output disabled.
 func NewMangoLogger(config *LogConfig) *MangoLogger {
-	// Future idea to have multiple "appenders" in the mangoLogger that one can add, each with it's own logging configuration that it looks at
+	logger, err := newMangoLogger(config)
+	if err != nil {
+		fmt.Printf("Invalid logger configuration, some outputs may not work. %s\n", err.Error())
+	}
+	return logger
+}
+
+func newMangoLogger(config *LogConfig) (*MangoLogger, error) {
+	var errs []error
+	if config == nil {
+		errs = append(errs, &ConfigError{Field: "config", Reason: "missing"})
+		config = &LogConfig{}
+	}
+	merged := config.clone()
+	merged.SetDefaults()
+	errs = append(errs, merged.Validate())
+
 	logger := &MangoLogger{
-		Config: applyDefaultFormats(*config),
+		Config: merged,
 		LogWriter: &lumberjack.Logger{
-			Filename:   config.Out.File.Path,
-			MaxSize:    config.Out.File.MaxSize,
-			MaxBackups: config.Out.File.MaxBackups,
-			MaxAge:     config.Out.File.MaxAge,
-			Compress:   config.Out.File.Compress,
+			Filename:   merged.Out.File.Path,
+			MaxSize:    merged.Out.File.MaxSize,
+			MaxBackups: merged.Out.File.MaxBackups,
+			MaxAge:     merged.Out.File.MaxAge,
+			Compress:   merged.Out.File.Compress,
 		},
+		levels:   newLevelNames(merged.MangoConfig.LevelNames),
+		opLevels: &operationLevels{},
+		contract: newContextContract(merged.MangoConfig),
+		redactor: newRedactor(merged.MangoConfig.Redaction),
 	}
-	return logger
+	// built-in appenders driven by OutConfig, more can be registered with AddAppender
+	logger.appenders = []Appender{
+		newCliAppender(merged.Out.Cli, newOutputLevel(OutputCli, logger.levels, func() (string, bool) {
+			return merged.Out.Cli.Level, merged.Out.Cli.Verbose
+		}), logger.levels, logger.contract.fieldNames()),
+		&fileAppender{
+			config: merged.Out.File,
+			writer: logger.LogWriter,
+			level: newOutputLevel(OutputFile, logger.levels, func() (string, bool) {
+				return merged.Out.File.Level, merged.Out.File.Debug
+			}),
+			formatter: newOutputEncoder(merged.Out.File.Encoding, merged.Out.File.FieldNames, logger.contract.fieldNames()),
+		},
+		&syslogAppender{
+			config: merged.Out.Syslog,
+			level: newOutputLevel(OutputSyslog, logger.levels, func() (string, bool) {
+				return merged.Out.Syslog.Level, true
+			}),
+			formatter: newOutputEncoder(merged.Out.Syslog.Encoding, merged.Out.Syslog.FieldNames, logger.contract.fieldNames()),
+		},
+	}
+	if merged.Out.Async != nil && merged.Out.Async.Enabled {
+		logger.async = newAsyncQueue(merged.Out.Async, logger.levels)
+	}
+	logger.recorder = newFlightRecorder(merged.Out.FlightRecorder, logger.levels)
+	logger.sampler = newSampler(merged.Out.Sampling, logger.levels, logger.writeSamplingSummary)
+	return logger, errors.Join(errs...)
 }
 
-// applyDefaultFormats to the configuration to ensure verbose and cli-friendly default formats are applied
-func applyDefaultFormats(config LogConfig) *LogConfig {
-	merged := config
-	if config.Out.Cli.VerboseFormat == "" {
-		merged.Out.Cli.VerboseFormat = DefaultVerboseFormat
-	}
-	if config.Out.Cli.FriendlyFormat == "" {
-		merged.Out.Cli.FriendlyFormat = DefaultFriendlyFormat
+// OutputLevel returns the minimum level of a built-in output (OutputCli, OutputFile or OutputSyslog).
+// The level follows the output's configuration (level, debug or verbose), even when changed on Config after New.
+// It can also be changed at runtime with Set, holding until that configuration changes, nil is returned for unknown outputs.
+func (sl MangoLogger) OutputLevel(output string) *slog.LevelVar {
+	for _, appender := range sl.appenders {
+		switch a := appender.(type) {
+		case *cliAppender:
+			if output == OutputCli {
+				return a.level.Var()
+			}
+		case *fileAppender:
+			if output == OutputFile {
+				return a.level.Var()
+			}
+		case *syslogAppender:
+			if output == OutputSyslog {
+				return a.level.Var()
+			}
+		}
 	}
-	return &merged
+	return nil
+}
+
+// SetOperationLevel overrides the minimum level of every output for the records logged with the given OPERATION in context
+func (sl MangoLogger) SetOperationLevel(operation string, level slog.Level) {
+	sl.opLevels.set(operation, level)
+}
+
+// OperationLevel returns the minimum level override of the operation, if any
+func (sl MangoLogger) OperationLevel(operation string) (slog.Level, bool) {
+	return sl.opLevels.get(operation)
+}
+
+// ResetOperationLevel removes the override of the operation, the output levels apply again
+func (sl MangoLogger) ResetOperationLevel(operation string) {
+	sl.opLevels.reset(operation)
+}
+
+// OperationLevels returns all the operation level overrides
+func (sl MangoLogger) OperationLevels() map[string]slog.Level {
+	return sl.opLevels.all()
 }
 
+// AddAppender registers additional outputs on the logger.
+// Register appenders before deriving handlers (WithAttrs/WithGroup or slog.Logger.With), as those take a copy of the current list.
+func (sl *MangoLogger) AddAppender(appenders ...Appender) {
+	sl.appenders = append(slices.Clip(sl.appenders), appenders...)
+}
+
+// Appenders returns the outputs currently registered on the logger
+func (sl MangoLogger) Appenders() []Appender {
+	return slices.Clone(sl.appenders)
+}
+
+// Enabled reports whether any enabled appender accepts the level, custom levels included,
+// or the flight recorder keeps it for the correlation id of the context
 func (sl MangoLogger) Enabled(context context.Context, level slog.Level) bool {
-	switch level {
-	case slog.LevelDebug:
-		fallthrough
-	case slog.LevelInfo:
-		fallthrough
-	case slog.LevelWarn:
-		fallthrough
-	case slog.LevelError:
-		return true
-	default:
+	if !sl.Config.Out.Enabled {
 		return false
 	}
+	operation, _ := OperationFrom(context)
+	if accepts(sl.withOperationLevel(operation, sl.enabledAppenders()), level) {
+		return true
+	}
+	_, recorded := sl.recordedCorrelationId(context)
+	return recorded && sl.recorder.records(level)
+}
+
+// accepts reports whether any of the appenders accepts the level
+func accepts(appenders []Appender, level slog.Level) bool {
+	for _, appender := range appenders {
+		if level >= appender.Level() {
+			return true
+		}
+	}
+	return false
+}
+
+// recordedCorrelationId is the correlation id the flight recorder keeps the records of, taken from the context
+// (or its trace id with CorrelationIdConfig.FromTraceId) as generated ones are never shared by other records
+func (sl MangoLogger) recordedCorrelationId(context context.Context) (string, bool) {
+	if sl.recorder == nil {
+		return "", false
+	}
+	if correlationId, ok := CorrelationIDFrom(context); ok {
+		return correlationId, true
+	}
+	if traceContext, ok := traceContextFrom(context); ok && sl.contract.fromTraceId {
+		return traceContext.TraceId, true
+	}
+	return "", false
 }
 
 func (sl MangoLogger) Handle(context context.Context, record slog.Record) error {
@@ -69,8 +212,9 @@ func (sl MangoLogger) Handle(context context.Context, record slog.Record) error
 		return nil
 	}
 
-	if !sl.Config.Out.File.Enabled && !sl.Config.Out.Cli.Enabled && sl.Config.Out.Syslog.Facility == "" {
-		fmt.Println("Effectively no logging enabled! The config.out.file.enabled, config.out.cli.enabled and config.out.syslog.facility flags are all false.")
+	appenders := sl.enabledAppenders()
+	if len(appenders) == 0 {
+		fmt.Println("Effectively no logging enabled! The config.out.file.enabled, config.out.cli.enabled and config.out.syslog.facility flags are all false and no other appender is enabled.")
 		return nil
 	}
 
@@ -79,159 +223,261 @@ func (sl MangoLogger) Handle(context context.Context, record slog.Record) error
 		return err
 	}
 
-	jsonOut, err := json.Marshal(log)
-	if err != nil {
-		fmt.Println("Failed to marshal the StructuredLog. Internal error, should never happen")
-		return err
+	// records are only sampled once the level filtering and the flight recorder have seen them,
+	// so that filtered records don't use up the sampling budget and sampled out ones are still recorded
+	appenders = sl.withOperationLevel(log.Operation, appenders)
+	correlationId, recorded := sl.recordedCorrelationId(context)
+	if !accepts(appenders, log.Level) {
+		if recorded && sl.recorder.records(log.Level) {
+			sl.recorder.record(correlationId, log)
+		}
+		return nil
 	}
-
-	if sl.Config.Out.Cli.Enabled {
-		err := sl.handlePromptOutput(log, string(jsonOut))
-		if err != nil {
+	if recorded && sl.recorder.triggers(log.Level) {
+		if err := sl.flushRecorded(correlationId, appenders); err != nil {
 			return err
 		}
 	}
-
-	if sl.Config.Out.File.Enabled {
-		err := sl.handleFileOutput(log, string(jsonOut))
-		if err != nil {
-			return err
+	if !sl.sampler.allow(record.Level, record.Message) {
+		if recorded && sl.recorder.records(log.Level) {
+			sl.recorder.record(correlationId, log)
 		}
+		return nil
 	}
+	return sl.dispatch(log, appenders)
+}
 
-	if sl.Config.Out.Syslog.Facility != "" {
-		err := sl.handleSyslogOutput(log, jsonOut)
-		if err != nil {
-			return err
+// flushRecorded writes the records kept by the flight recorder for the correlation id, oldest first,
+// to the appenders regardless of their level
+func (sl MangoLogger) flushRecorded(correlationId string, appenders []Appender) error {
+	var errs []error
+	for _, log := range sl.recorder.take(correlationId) {
+		recordedAppenders := make([]Appender, len(appenders))
+		for i, appender := range appenders {
+			recordedAppenders[i] = levelOverride{Appender: appender, level: log.Level}
 		}
+		errs = append(errs, sl.dispatch(log, recordedAppenders))
 	}
-
-	return nil
+	return errors.Join(errs...)
 }
 
-func (sl MangoLogger) WithAttrs(attrs []slog.Attr) slog.Handler {
-	sl.attrs = append(sl.attrs, attrs...)
-	return sl
+// dispatch hands the log over to the async worker, or writes it right away when async mode is not enabled
+func (sl MangoLogger) dispatch(log *StructuredLog, appenders []Appender) error {
+	if sl.async != nil {
+		return sl.async.enqueue(asyncEntry{log: log, appenders: appenders})
+	}
+	return write(log, appenders)
 }
 
-func (sl MangoLogger) WithGroup(name string) slog.Handler {
-	panic("unimplemented") // add a way of storing a generic map of maps of attributes
+// writeSamplingSummary logs how many records were suppressed by sampling and rate limiting since the last summary
+// It is called on the root logger, so it reaches every appender registered with AddAppender
+func (sl *MangoLogger) writeSamplingSummary(sampled, rateLimited map[slog.Level]uint64) {
+	if !sl.Config.Out.Enabled {
+		return
+	}
+	var sampledTotal, rateLimitedTotal uint64
+	byLevel := make(map[string]uint64)
+	for level, count := range sampled {
+		sampledTotal += count
+		byLevel[sl.levels.name(level)] += count
+	}
+	for level, count := range rateLimited {
+		rateLimitedTotal += count
+		byLevel[sl.levels.name(level)] += count
+	}
+	levels := make([]any, 0, len(byLevel))
+	for _, name := range slices.Sorted(maps.Keys(byLevel)) {
+		levels = append(levels, slog.Uint64(name, byLevel[name]))
+	}
+
+	record := slog.NewRecord(time.Now(), slog.LevelWarn, SamplingSummaryMessage, 0)
+	record.Add(
+		slog.Uint64("suppressed", sampledTotal+rateLimitedTotal),
+		slog.Uint64("sampled", sampledTotal),
+		slog.Uint64("rateLimited", rateLimitedTotal),
+		slog.Group("levels", levels...),
+	)
+	log := MangoLogger{Config: sl.Config, levels: sl.levels}.makeBaseLog(record)
+	log.Type = PerformanceType
+	log.Operation = samplingOperation
+
+	if err := sl.dispatch(log, sl.withOperationLevel(log.Operation, sl.enabledAppenders())); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to write log entry %s: %v\n", log.LogId, err)
+	}
 }
 
-func (sl MangoLogger) writeStringToLogFile(s string) error {
-	if sl.Config.Out.Enabled {
-		s += "\n"
-		b := unsafe.Slice(unsafe.StringData(s), len(s))
-		_, err := sl.LogWriter.Write(b)
+// write formats and appends the log to each appender accepting its level
+func write(log *StructuredLog, appenders []Appender) error {
+	var errs []error
+	for _, appender := range appenders {
+		if log.Level < appender.Level() {
+			continue
+		}
+		formatted, err := appender.Formatter().Format(log)
 		if err != nil {
-			return err
+			fmt.Println("Failed to format the StructuredLog.")
+			errs = append(errs, err)
+			continue
+		}
+		if err := appender.Append(log, formatted); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return nil
+
+	return errors.Join(errs...)
 }
 
-func formatWithGoJQ(obj string, query string) (string, error) {
-	// Unmarshal the JSON into an interface{} so gojq can process it
-	var objInterface map[string]interface{}
-	if err := json.Unmarshal([]byte(obj), &objInterface); err != nil {
-		return "", err
+// Flush waits until all the records handed over to the async worker before the call are written.
+// It returns immediately when async mode is not enabled.
+func (sl MangoLogger) Flush(ctx context.Context) error {
+	if sl.async == nil {
+		return nil
 	}
+	return sl.async.flush(ctx)
+}
 
-	// Parse the jq query
-	jqQuery, err := gojq.Parse(query)
-	if err != nil {
-		return "", err
+// Close writes the last sampling summary (if enabled), drains the async queue (if enabled) and closes every appender implementing io.Closer,
+// such as the log file and the syslog connections.
+// Records logged after Close starts are rejected with ErrLoggerClosed in async mode, and the records still queued when ctx is done are discarded.
+func (sl MangoLogger) Close(ctx context.Context) error {
+	var errs []error
+	sl.sampler.close()
+	if sl.async != nil {
+		errs = append(errs, sl.async.close(ctx))
+	}
+	for _, appender := range sl.appenders {
+		if closer, ok := appender.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
 	}
+	return errors.Join(errs...)
+}
 
-	// Create a jq code executor
-	iter := jqQuery.Run(objInterface)
+// Dropped is the number of records discarded by the async overflow policy
+func (sl MangoLogger) Dropped() uint64 {
+	if sl.async == nil {
+		return 0
+	}
+	return sl.async.dropped.Load()
+}
 
-	// Retrieve the result
-	var result interface{}
-	for {
-		v, ok := iter.Next()
-		if !ok {
-			break
-		}
-		if err, ok := v.(error); ok {
-			return "", err
-		}
-		result = v
+// Suppressed is the number of records discarded by sampling and rate limiting
+func (sl MangoLogger) Suppressed() uint64 {
+	if sl.sampler == nil {
+		return 0
 	}
+	return sl.sampler.suppressed.Load()
+}
 
-	// Convert result to string
-	resultStr, err := json.Marshal(result)
-	if err != nil {
-		return "", err
+// Evicted is the number of records the flight recorder discarded, overwritten in a full buffer or evicted with their correlation id
+func (sl MangoLogger) Evicted() uint64 {
+	if sl.recorder == nil {
+		return 0
 	}
+	return sl.recorder.evicted.Load()
+}
 
-	return string(resultStr), nil
+// withOperationLevel applies the level override of the operation (if any) to the appenders
+func (sl MangoLogger) withOperationLevel(operation string, appenders []Appender) []Appender {
+	level, ok := sl.opLevels.get(operation)
+	if !ok {
+		return appenders
+	}
+	overridden := make([]Appender, len(appenders))
+	for i, appender := range appenders {
+		overridden[i] = levelOverride{Appender: appender, level: level}
+	}
+	return overridden
 }
 
-func (sl MangoLogger) handleFileOutput(log *StructuredLog, jsonOut string) error {
-	switch log.Level {
-	case slog.LevelDebug:
-		if sl.Config.Out.File.Debug {
-			return sl.writeStringToLogFile(jsonOut)
+func (sl MangoLogger) enabledAppenders() []Appender {
+	var enabled []Appender
+	for _, appender := range sl.appenders {
+		if appender.Enabled() {
+			enabled = append(enabled, appender)
 		}
-	case slog.LevelInfo:
-		return sl.writeStringToLogFile(jsonOut)
-	case slog.LevelWarn:
-		fallthrough
-	case slog.LevelError:
-		return sl.writeStringToLogFile(jsonOut)
-	default:
-		fmt.Println("Record level not one of: debug, info, warn or error")
-		return fmt.Errorf("record level not one of: debug, info, warn or error")
 	}
-	return nil
+	return enabled
 }
 
-func (sl MangoLogger) handlePromptOutput(log *StructuredLog, jsonOut string) error {
-	switch log.Level {
-	case slog.LevelDebug:
-		if sl.Config.Out.Cli.Verbose {
-			result, _ := formatWithGoJQ(jsonOut, sl.Config.Out.Cli.VerboseFormat)
-			_, _ = fmt.Fprintln(os.Stdout, result)
-		}
-	case slog.LevelInfo:
-		if sl.Config.Out.Cli.Friendly {
-			result, _ := formatWithGoJQ(jsonOut, sl.Config.Out.Cli.FriendlyFormat)
-			_, _ = fmt.Fprintln(os.Stdout, result)
-		} else {
-			_, _ = fmt.Fprintln(os.Stdout, jsonOut)
-		}
-	case slog.LevelWarn:
-		fallthrough
-	case slog.LevelError:
-		if sl.Config.Out.Cli.Friendly {
-			result, _ := formatWithGoJQ(jsonOut, sl.Config.Out.Cli.FriendlyFormat)
-			_, _ = fmt.Fprintln(os.Stderr, result)
-		} else {
-			_, _ = fmt.Fprintln(os.Stderr, jsonOut)
-		}
-	default:
-		fmt.Println("Record level not one of: debug, info, warn or error")
-		return fmt.Errorf("record level not one of: debug, info, warn or error")
+func (sl MangoLogger) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return sl
 	}
-	return nil
+	// clip to avoid sharing the backing array between derived handlers
+	sl.attrs = append(slices.Clip(sl.attrs), wrapInGroups(sl.groups, attrs)...)
+	return sl
 }
 
-// mergeAttrs with list2 taking precedence
-func mergeAttrs(list1, list2 []slog.Attr) []slog.Attr {
-	attrMap := make(map[string]slog.Attr)
-	for _, attr := range list1 {
-		attrMap[attr.Key] = attr
+func (sl MangoLogger) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return sl
 	}
-	for _, attr := range list2 {
-		attrMap[attr.Key] = attr
+	sl.groups = append(slices.Clip(sl.groups), name)
+	return sl
+}
+
+// wrapInGroups nests the attrs inside the (currently open) groups, outermost group first
+func wrapInGroups(groups []string, attrs []slog.Attr) []slog.Attr {
+	if len(attrs) == 0 {
+		return nil
 	}
-	mergedAttrs := make([]slog.Attr, 0, len(attrMap))
-	for _, attr := range attrMap {
-		mergedAttrs = append(mergedAttrs, attr)
+	for i := len(groups) - 1; i >= 0; i-- {
+		attrs = []slog.Attr{{Key: groups[i], Value: slog.GroupValue(attrs...)}}
 	}
+	return attrs
+}
 
-	return mergedAttrs
+// mergeAttrs combines the attrs of both lists in order, resolving their values
+// Groups with an empty key are inlined and groups sharing a key are combined, the other duplicate keys being resolved with the policy
+func mergeAttrs(list1, list2 []slog.Attr, policy DuplicateKeyPolicy) []slog.Attr {
+	var merged []slog.Attr
+	index := make(map[string]int)
+	var add func(attrs []slog.Attr)
+	add = func(attrs []slog.Attr) {
+		for _, attr := range attrs {
+			attr.Value = attr.Value.Resolve()
+			if attr.Equal(slog.Attr{}) || isGroup(attr) && len(attr.Value.Group()) == 0 {
+				continue
+			}
+			if isGroup(attr) && attr.Key == "" {
+				add(attr.Value.Group())
+				continue
+			}
+			i, exists := index[attr.Key]
+			switch {
+			case !exists:
+				index[attr.Key] = len(merged)
+				merged = append(merged, attr)
+			case isGroup(merged[i]) && isGroup(attr):
+				merged[i].Value = slog.GroupValue(slices.Concat(merged[i].Value.Group(), attr.Value.Group())...)
+			case policy == DuplicateFirstWins:
+			case policy == DuplicateKeepBoth:
+				attr.Key = suffixedKey(attr.Key, index)
+				index[attr.Key] = len(merged)
+				merged = append(merged, attr)
+			default:
+				merged[i] = attr
+			}
+		}
+	}
+	add(list1)
+	add(list2)
+	return merged
+}
+
+// suffixedKey is the first free key#2, key#3... for DuplicateKeepBoth
+func suffixedKey(key string, index map[string]int) string {
+	for n := 2; ; n++ {
+		suffixed := key + "#" + strconv.Itoa(n)
+		if _, taken := index[suffixed]; !taken {
+			return suffixed
+		}
+	}
+}
+
+func isGroup(attr slog.Attr) bool {
+	return attr.Value.Kind() == slog.KindGroup
 }
 
 func getAllAttrs(record slog.Record) []slog.Attr {
@@ -246,20 +492,17 @@ func getAllAttrs(record slog.Record) []slog.Attr {
 }
 
 func (sl MangoLogger) handleRequiredFields(context context.Context, logOutput *StructuredLog) error {
-	if sl.Config.MangoConfig.CorrelationId.Strict {
-		REQUIRED_FIELDS = append(REQUIRED_FIELDS, CORRELATION_ID)
-	}
-	for _, label := range REQUIRED_FIELDS {
+	for _, label := range sl.contract.required {
 		err := handleEachField(context, logOutput, label, sl)
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return sl.contract.handleContextFields(context, logOutput)
 }
 
 func handleEachField(context context.Context, logOutput *StructuredLog, label ctxKey, sl MangoLogger) error {
-	if value, ok := context.Value(label).(string); !ok {
+	if value, ok := contextString(context, label); !ok {
 		err := handleValueMissing(label, sl, logOutput)
 		if err != nil {
 			return err
@@ -275,13 +518,15 @@ func handleEachField(context context.Context, logOutput *StructuredLog, label ct
 
 func handleValueMissing(label ctxKey, sl MangoLogger, logOutput *StructuredLog) error {
 	if CORRELATION_ID == label {
-		if sl.Config.MangoConfig.CorrelationId.AutoGenerate {
+		if sl.contract.fromTraceId && logOutput.TraceId != "" {
+			logOutput.Correlationid = logOutput.TraceId
+		} else if sl.contract.autoGenerate {
 			logOutput.Correlationid = uuid.New().String() // generate new UUID for correlation if missing from context
 		} else {
 			return fmt.Errorf("%w - required in context and not present (or wrong type - expected string). This can be added by doing: context.WithValue(newCtx, mangologger.%s, \"desiredValue\")", errStrictModeOn, label)
 		}
 	} else {
-		if sl.Config.MangoConfig.Strict {
+		if sl.contract.strict {
 			return fmt.Errorf("%w - required in context and not present (or wrong type - expected string). This can be added by doing: context.WithValue(newCtx, mangologger.%s, \"desiredValue\")", errStrictModeOn, label)
 		}
 	}
@@ -295,9 +540,9 @@ func handleExistentValues(label ctxKey, logOutput *StructuredLog, value string,
 	case APPLICATION:
 		logOutput.Application = value
 	case TYPE:
-		if sl.Config.MangoConfig.Strict {
-			if !slices.Contains(ALLOWED_TYPES, value) {
-				return fmt.Errorf("%w - [%s] required in context and not present (or wrong type - expected string). Current value [%s] is not in the allowed list: %+q", errStrictModeOn, label, value, ALLOWED_TYPES)
+		if sl.contract.strict {
+			if !slices.Contains(sl.contract.allowedTypes, value) {
+				return fmt.Errorf("%w - [%s] required in context and not present (or wrong type - expected string). Current value [%s] is not in the allowed list: %+q", errStrictModeOn, label, value, sl.contract.allowedTypes)
 			}
 		}
 		logOutput.Type = value
@@ -307,6 +552,7 @@ func handleExistentValues(label ctxKey, logOutput *StructuredLog, value string,
 
 func (sl MangoLogger) buildLog(context context.Context, record slog.Record) (*StructuredLog, error) {
 	logOutput := sl.makeBaseLog(record)
+	setTraceContext(context, logOutput)
 
 	err := sl.handleRequiredFields(context, logOutput)
 	if err != nil {
@@ -314,23 +560,36 @@ func (sl MangoLogger) buildLog(context context.Context, record slog.Record) (*St
 		return logOutput, err
 	}
 
-	if value, ok := context.Value(CORRELATION_ID).(string); ok {
+	if value, ok := CorrelationIDFrom(context); ok {
 		logOutput.Correlationid = value
+	} else if logOutput.Correlationid == "" && sl.contract.fromTraceId {
+		logOutput.Correlationid = logOutput.TraceId
 	}
 
+	sl.redactor.redact(logOutput)
 	return logOutput, nil
 }
 
 func (sl MangoLogger) makeBaseLog(record slog.Record) *StructuredLog {
 	logOutput := &StructuredLog{}
-	logOutput.Timestamp = record.Time.Format(RFC3339NanoMC)
+	if !record.Time.IsZero() { // zero time is ignored as per slog.Handler contract
+		logOutput.Timestamp = record.Time.Format(RFC3339NanoMC)
+	}
 	logOutput.LogId = uuid.New().String() // generate a new UUID for each log entry
 	logOutput.Level = record.Level
+	logOutput.LevelName = sl.levels.name(record.Level)
 	logOutput.Operation = "unknownOperation"
 	logOutput.Application = "unknownApplication"
 	logOutput.Type = "unknownType"
 	logOutput.Correlationid = ""
 	logOutput.Message = record.Message
-	logOutput.Attributes = ToMap(mergeAttrs(sl.attrs, getAllAttrs(record)))
+	policy := sl.Config.MangoConfig.DuplicateKeys
+	logOutput.Attributes = toAttributes(mergeAttrs(sl.attrs, wrapInGroups(sl.groups, getAllAttrs(record)), policy), policy)
+	if sl.Config.MangoConfig.AddSource && record.PC != 0 {
+		logOutput.Source = recordSource(record.PC)
+	}
+	if sl.Config.MangoConfig.ErrorStack && record.Level >= slog.LevelError {
+		logOutput.Stack = callerStack(record.PC)
+	}
 	return logOutput
 }
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
+	"math"
 	"os"
 	"testing"
+	"testing/slogtest"
 	"time"
 
 	"github.com/stretchr/testify/assert"
@@ -45,6 +49,16 @@ func newTestLogger(cliEnabled, fileEnabled bool, strict bool, autoGenCorr bool)
 	return NewMangoLogger(config)
 }
 
+// cliAppenderOf returns the built-in cli appender of the logger
+func cliAppenderOf(logger *MangoLogger) *cliAppender {
+	return logger.appenders[0].(*cliAppender)
+}
+
+// fileAppenderOf returns the built-in file appender of the logger
+func fileAppenderOf(logger *MangoLogger) *fileAppender {
+	return logger.appenders[1].(*fileAppender)
+}
+
 func TestMangoLogger_AllLevels(t *testing.T) {
 	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
 	for _, lvl := range levels {
@@ -143,7 +157,7 @@ func TestMangoLogger_MergeAttrs(t *testing.T) {
 	a1 := []slog.Attr{{Key: "a", Value: slog.StringValue("1")}}
 	a2 := []slog.Attr{{Key: "b", Value: slog.StringValue("2")}, {Key: "a", Value: slog.StringValue("3")}}
 
-	merged := mergeAttrs(a1, a2)
+	merged := mergeAttrs(a1, a2, DuplicateLastWins)
 	assert.Len(t, merged, 2)
 
 	m := make(map[string]string)
@@ -153,16 +167,75 @@ func TestMangoLogger_MergeAttrs(t *testing.T) {
 
 	assert.Equal(t, "3", m["a"]) // list2 takes precedence
 	assert.Equal(t, "2", m["b"])
+	assert.Equal(t, "a", merged[0].Key) // in order of first appearance
 }
 
 func TestFormatWithGoJQ_ErrorCases(t *testing.T) {
-	// invalid JSON
-	_, err := formatWithGoJQ("{invalid}", ".")
-	assert.Error(t, err)
-
 	// invalid jq
-	_, err = formatWithGoJQ(`{"a":1}`, "???")
+	_, err := compileGoJQ("???")
 	assert.Error(t, err)
+
+	// runtime error
+	code, err := compileGoJQ(`error("boom")`)
+	assert.NoError(t, err)
+	_, err = formatWithGoJQ(code, &StructuredLog{})
+	assert.ErrorContains(t, err, "boom")
+
+	// no result
+	code, err = compileGoJQ("empty")
+	assert.NoError(t, err)
+	_, err = formatWithGoJQ(code, &StructuredLog{})
+	assert.ErrorIs(t, err, errNoGoJQResult)
+}
+
+func TestFormatWithGoJQ_InMemoryLog(t *testing.T) {
+	type point struct {
+		X int `json:"x"`
+	}
+	log := &StructuredLog{
+		LevelName: "INFO",
+		Message:   "hello",
+		Attributes: attributesOf(
+			"count", int64(41),
+			"ratio", float32(0.5),
+			"big", uint64(math.MaxUint64),
+			"when", time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC),
+			"level", slog.LevelWarn,
+			"point", point{X: 3},
+			"nested", map[string]interface{}{"ok": true},
+			"list", []any{uint8(1), "two"},
+			"duration", time.Second,
+		),
+	}
+	code, err := compileGoJQ(`[.level, .message, .attributes.count + 1, .attributes.ratio, .attributes.when, .attributes.level, .attributes.point.x, .attributes.nested.ok, .attributes.list[0], .attributes.duration, .attributes.big > 0]`)
+	assert.NoError(t, err)
+
+	result, err := formatWithGoJQ(code, log)
+	assert.NoError(t, err)
+	assert.Equal(t, `["INFO","hello",42,0.5,"2025-01-15T09:00:00Z","WARN",3,true,1,1000000000,true]`, string(result))
+}
+
+func TestCliAppender_FormatFallback(t *testing.T) {
+	log := &StructuredLog{Level: slog.LevelInfo, LevelName: "INFO", Message: "fallback"}
+	jsonOut, _ := JSONFormatter.Format(log)
+
+	// invalid format to compile
+	appender := newCliAppender(&CliConfig{Friendly: true, FriendlyFormat: "???", VerboseFormat: "."}, nil, levelNames{}, nil)
+	result, err := appender.Formatter().Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonOut, result)
+
+	// format failing at runtime
+	appender = newCliAppender(&CliConfig{Friendly: true, FriendlyFormat: ".message | tonumber", VerboseFormat: "."}, nil, levelNames{}, nil)
+	result, err = appender.Formatter().Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, jsonOut, result)
+
+	// working format
+	appender = newCliAppender(&CliConfig{Friendly: true, FriendlyFormat: `"[\(.level)] \(.message)"`, VerboseFormat: "."}, nil, levelNames{}, nil)
+	result, err = appender.Formatter().Format(log)
+	assert.NoError(t, err)
+	assert.Equal(t, `[INFO] fallback`, string(result))
 }
 
 func TestHandleEachField_ExistingAndMissing(t *testing.T) {
@@ -185,8 +258,13 @@ func TestHandleEachField_ExistingAndMissing(t *testing.T) {
 
 func TestWriteStringToLogFile_Disabled(t *testing.T) {
 	logger := newTestLogger(true, false, false, true)
-	err := logger.writeStringToLogFile("hello")
-	assert.NoError(t, err) // should silently skip since file is disabled
+	err := logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0))
+	assert.NoError(t, err)
+
+	// should silently skip since file is disabled
+	content, err := os.ReadFile(logger.Config.Out.File.Path)
+	assert.NoError(t, err)
+	assert.Empty(t, content)
 }
 
 func TestHandlePromptOutput_DefaultFallback(t *testing.T) {
@@ -201,7 +279,7 @@ func TestHandlePromptOutput_DefaultFallback(t *testing.T) {
 	r, w, _ := os.Pipe()
 	os.Stdout = w
 
-	err := logger.handlePromptOutput(record, `{"message":"hello"}`)
+	err := cliAppenderOf(logger).Append(record, []byte(`{"message":"hello"}`))
 	assert.NoError(t, err)
 
 	_ = w.Close()
@@ -259,7 +337,7 @@ func TestHandleFileOutput_AllLevels(t *testing.T) {
 			jsonOut, err := json.Marshal(logOutput)
 			assert.NoError(t, err)
 
-			err = logger.handleFileOutput(logOutput, string(jsonOut))
+			err = fileAppenderOf(logger).Append(logOutput, jsonOut)
 			assert.NoError(t, err)
 
 			// Read file content
@@ -302,45 +380,292 @@ func TestHandleFileOutput_FileDisabled(t *testing.T) {
 	jsonOut := `{"Level":"INFO","Message":"Should not write"}`
 
 	// Should not error even if file is disabled
-	err := logger.handleFileOutput(logOutput, jsonOut)
+	err := fileAppenderOf(logger).Append(logOutput, []byte(jsonOut))
 	assert.NoError(t, err)
 }
 
 func TestHandleFileOutput_InvalidLevel(t *testing.T) {
-	// Logger config with file enabled
-	tmpFile, err := os.CreateTemp("", "logger_test_invalid_level_*.log")
+	logger, buf := newBufferedLogger(t, &LogConfig{}, slog.LevelDebug, nil)
+
+	// a level without a name is still written, and read back
+	assert.NoError(t, logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.Level(999), "Invalid level message", 0)))
+	assert.Contains(t, buf.String(), `"level":"ERROR+991","message":"Invalid level message"`)
+
+	var log StructuredLog
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, slog.Level(999), log.Level)
+	assert.Equal(t, "ERROR+991", log.LevelName)
+}
+
+func TestStructuredLog_UnmarshalJSON(t *testing.T) {
+	written, err := JSONFormatter.Format(&StructuredLog{
+		Timestamp:  "2025-01-15T09:53:34.717Z",
+		Level:      slog.LevelWarn,
+		LevelName:  "WARN",
+		Message:    "low stock",
+		Fields:     map[string]string{"tenant": "acme"},
+		Attributes: attributesOf("items", 3),
+	})
 	assert.NoError(t, err)
-	defer func(name string) {
-		_ = os.Remove(name)
-	}(tmpFile.Name())
-	defer func(tmpFile *os.File) {
-		_ = tmpFile.Close()
-	}(tmpFile)
 
-	config := &LogConfig{
-		Out: &OutConfig{
-			Enabled: true,
-			File: &FileOutputConfig{
-				Enabled: true,
-				Path:    tmpFile.Name(),
+	var log StructuredLog
+	assert.NoError(t, json.Unmarshal(written, &log))
+	assert.Equal(t, slog.LevelWarn, log.Level)
+	assert.Equal(t, "WARN", log.LevelName)
+	assert.Equal(t, "low stock", log.Message)
+	assert.Equal(t, map[string]string{"tenant": "acme"}, log.Fields)
+	assert.Equal(t, map[string]interface{}{"items": float64(3)}, log.Attributes.Map())
+
+	// custom level names are not known outside the logger
+	assert.NoError(t, json.Unmarshal([]byte(`{"level":"TRACE","message":"m"}`), &log))
+	assert.Equal(t, "TRACE", log.LevelName)
+	assert.Equal(t, slog.LevelInfo, log.Level)
+	assert.Error(t, json.Unmarshal([]byte(`{"level":1}`), &log))
+}
+
+func TestMangoLogger_CustomLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(false, false, false, true)
+	logger.Config.MangoConfig.LevelNames = map[string]int{"trace": -8, "NOTICE": 2, "FATAL": 12}
+	logger.Config.Out.File.Level = "TRACE"
+	logger = NewMangoLogger(logger.Config)
+	logger.AddAppender(NewWriterAppender(&buf, slog.LevelDebug-4, FormatterFunc(func(log *StructuredLog) ([]byte, error) {
+		return []byte(log.LevelName), nil
+	})))
+
+	assert.Equal(t, LevelTrace, logger.OutputLevel(OutputFile).Level())
+	assert.True(t, logger.Enabled(context.Background(), LevelTrace))
+	assert.False(t, logger.Enabled(context.Background(), LevelTrace-1))
+
+	l := slog.New(logger)
+	for _, level := range []slog.Level{LevelTrace, slog.LevelDebug, LevelNotice, LevelFatal, slog.LevelError + 1} {
+		l.Log(context.Background(), level, "custom")
+	}
+	assert.Equal(t, "TRACE\nDEBUG\nNOTICE\nFATAL\nERROR+1\n", buf.String())
+}
+
+func TestMangoLogger_OutputLevelAtRuntime(t *testing.T) {
+	logger := newTestLogger(false, true, false, true)
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug))
+
+	logger.OutputLevel(OutputFile).Set(slog.LevelDebug)
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelDebug))
+	assert.Nil(t, logger.OutputLevel("unknown"))
+
+	logger.Config.Out.Enabled = false
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelError))
+}
+
+func TestLevelNames_Parse(t *testing.T) {
+	names := newLevelNames(map[string]int{"Trace": -8})
+	for text, expected := range map[string]slog.Level{
+		"trace":   LevelTrace,
+		"TRACE":   LevelTrace,
+		"warn":    slog.LevelWarn,
+		"DEBUG-4": LevelTrace,
+		"12":      LevelFatal,
+		" -8 ":    LevelTrace,
+	} {
+		level, err := names.parse(text)
+		assert.NoError(t, err, text)
+		assert.Equal(t, expected, level, text)
+	}
+	_, err := names.parse("verbose")
+	assert.Error(t, err)
+
+	// invalid levels are rejected by New, NewMangoLogger falling back to the default
+	logger := newTestLogger(false, true, false, true)
+	logger.Config.Out.File.Level = "verbose"
+	_, err = New(logger.Config)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, `out.file.level: unknown log level "verbose"`)
+	logger = NewMangoLogger(logger.Config)
+	assert.Equal(t, slog.LevelInfo, logger.OutputLevel(OutputFile).Level())
+
+	// a typo made after New falls back to the default and is reported
+	logger.Config.Out.File.Level = "WARN"
+	assert.Equal(t, slog.LevelWarn, logger.OutputLevel(OutputFile).Level())
+	oldOut := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	logger.Config.Out.File.Level = "WARNN"
+	level := logger.OutputLevel(OutputFile).Level()
+	_ = w.Close()
+	os.Stdout = oldOut
+	printed, _ := io.ReadAll(r)
+	assert.Equal(t, slog.LevelInfo, level)
+	assert.Equal(t, "Invalid file output level, using INFO. unknown log level \"WARNN\"\n", string(printed))
+}
+
+func TestMangoLogger_SlogTestHandler(t *testing.T) {
+	logger := newTestLogger(false, true, false, true)
+	logger.Config.Out.File.Debug = true
+
+	results := func() []map[string]any {
+		content, err := os.ReadFile(logger.LogWriter.Filename)
+		assert.NoError(t, err)
+
+		var ms []map[string]any
+		for _, line := range bytes.Split(bytes.TrimSpace(content), []byte("\n")) {
+			var log StructuredLog
+			assert.NoError(t, json.Unmarshal(line, &log))
+
+			// map the StructuredLog contract onto the keys slogtest expects
+			m := log.Attributes.Map()
+			if log.Timestamp != "" {
+				m[slog.TimeKey] = log.Timestamp
+			}
+			m[slog.LevelKey] = log.LevelName
+			m[slog.MessageKey] = log.Message
+			ms = append(ms, m)
+		}
+		return ms
+	}
+
+	err := slogtest.TestHandler(logger, results)
+	assert.NoError(t, err)
+}
+
+func TestMangoLogger_WithGroup(t *testing.T) {
+	logger := newTestLogger(false, false, false, true)
+
+	handler := logger.WithAttrs([]slog.Attr{slog.String("top", "1")}).
+		WithGroup("g1").
+		WithAttrs([]slog.Attr{slog.String("a", "b")}).
+		WithGroup("g2").
+		WithGroup("empty").(MangoLogger)
+
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "grouped", 0)
+	record.AddAttrs(slog.Int("n", 1), slog.Group("inner", slog.String("k", "v")), slog.Group("dropped"))
+
+	logOutput, err := handler.buildLog(context.Background(), record)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"top": "1",
+		"g1": map[string]interface{}{
+			"a": "b",
+			"g2": map[string]interface{}{
+				"empty": map[string]interface{}{
+					"n":     int64(1),
+					"inner": map[string]interface{}{"k": "v"},
+				},
 			},
-			Cli:    &CliConfig{Enabled: false},
-			Syslog: &SyslogConfig{},
 		},
-		MangoConfig: &MangoConfig{},
+	}, logOutput.Attributes.Map())
+
+	// a group without any attributes is dropped
+	emptyRecord := slog.NewRecord(time.Now(), slog.LevelInfo, "no attrs", 0)
+	logOutput, err = handler.buildLog(context.Background(), emptyRecord)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"top": "1", "g1": map[string]interface{}{"a": "b"}}, logOutput.Attributes.Map())
+}
+
+func TestNew_PartialConfig(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "partial-*.log")
+	assert.NoError(t, err)
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	// only the file output is configured, everything else is defaulted
+	logger, err := New(&LogConfig{
+		Out: &OutConfig{Enabled: true, File: &FileOutputConfig{Enabled: true, Path: tmpFile.Name()}},
+	})
+	assert.NoError(t, err)
+	assert.False(t, logger.Config.Out.Cli.Enabled)
+	assert.Empty(t, logger.Config.Out.Syslog.Facility)
+
+	slog.New(logger).Info("partial config")
+
+	content, err := os.ReadFile(tmpFile.Name())
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "partial config")
+}
+
+func TestNew_ConfigNotModified(t *testing.T) {
+	config := &LogConfig{
+		MangoConfig: &MangoConfig{Redaction: &RedactionConfig{Enabled: true}, LevelNames: map[string]int{"TRACE": -8}},
+		Out:         &OutConfig{Enabled: true, Cli: &CliConfig{}, File: &FileOutputConfig{FieldNames: map[string]string{}}},
 	}
+	first, err := New(config)
+	assert.NoError(t, err)
+	second, err := New(config)
+	assert.NoError(t, err)
 
-	logger := NewMangoLogger(config)
+	// the defaults are applied to copies of the sub-configs
+	assert.Equal(t, &CliConfig{}, config.Out.Cli)
+	assert.Equal(t, &RedactionConfig{Enabled: true}, config.MangoConfig.Redaction)
+	assert.Nil(t, config.MangoConfig.CorrelationId)
+	assert.Nil(t, config.Out.Syslog)
+	assert.Equal(t, ColorAuto, first.Config.Out.Cli.Color)
+
+	// the loggers don't share their configuration
+	first.Config.Out.Cli.Enabled = true
+	first.Config.MangoConfig.LevelNames["FATAL"] = 12
+	assert.False(t, second.Config.Out.Cli.Enabled)
+	assert.False(t, config.Out.Cli.Enabled)
+	assert.Equal(t, map[string]int{"TRACE": -8}, config.MangoConfig.LevelNames)
+}
 
-	// Use a non-standard level
-	logOutput := &StructuredLog{
-		Level:   slog.Level(999), // invalid level
-		Message: "Invalid level message",
+func TestNew_EmptyConfig(t *testing.T) {
+	logger, err := New(&LogConfig{})
+	assert.NoError(t, err)
+	assert.NotPanics(t, func() {
+		assert.NoError(t, logger.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "nothing enabled", 0)))
+	})
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	logger, err := New(nil)
+	assert.Nil(t, logger)
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.ErrorContains(t, err, "config: missing")
+
+	logger, err = New(&LogConfig{Out: &OutConfig{File: &FileOutputConfig{MaxSize: -1}, Syslog: &SyslogConfig{Facility: "nowhere"}}})
+	assert.Nil(t, logger)
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr))
+	assert.ErrorContains(t, err, "out.file.max-size")
+	assert.ErrorContains(t, err, "out.syslog.facility")
+}
+
+func TestNewMangoLogger_InvalidConfigStillCreated(t *testing.T) {
+	assert.NotPanics(t, func() {
+		logger := NewMangoLogger(nil)
+		assert.NotNil(t, logger)
+		assert.False(t, logger.Enabled(context.Background(), slog.LevelError))
+	})
+
+	logger := NewMangoLogger(&LogConfig{Out: &OutConfig{Cli: &CliConfig{Level: "LOUD"}}})
+	assert.Equal(t, slog.LevelInfo, logger.OutputLevel(OutputCli).Level())
+}
+
+func TestMangoLogger_AttributeOrder(t *testing.T) {
+	logger, buf := newBufferedLogger(t, &LogConfig{}, slog.LevelInfo, nil)
+
+	l := slog.New(logger).With("zeta", 1, "alpha", 2).WithGroup("req").With("method", "GET")
+	for range 20 {
+		l.Info("ordered", "status", 200, "bytes", 12, slog.Group("", "inlined", true))
+		assert.Contains(t, buf.String(), `"attributes":{"zeta":1,"alpha":2,"req":{"method":"GET","status":200,"bytes":12,"inlined":true}}`)
+		buf.Reset()
 	}
 
-	jsonOut := `{"Level":999,"Message":"Invalid level message"}`
+	console, _ := NewConsoleFormatter(false).Format(&StructuredLog{Message: "m", Attributes: attributesOf("zeta", 1, "alpha", 2)})
+	assert.Contains(t, string(console), "zeta=1  alpha=2")
+}
 
-	err = logger.handleFileOutput(logOutput, jsonOut)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "record level not one of")
+func TestMangoLogger_DuplicateKeys(t *testing.T) {
+	expected := map[DuplicateKeyPolicy]string{
+		DuplicateLastWins:  `{"user":"record","id":3,"id#2":"x","g":{"a":1,"b":2}}`,
+		DuplicateFirstWins: `{"user":"logger","id":1,"id#2":"x","g":{"a":1,"b":2}}`,
+		DuplicateKeepBoth:  `{"user":"logger","id":1,"id#2":"x","g":{"a":1,"b":2},"user#2":"record","id#3":3}`,
+	}
+	for policy, attributes := range expected {
+		logger, buf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{DuplicateKeys: policy}}, slog.LevelInfo, nil)
+
+		slog.New(logger).With("user", "logger", "id", 1, "id#2", "x", slog.Group("g", "a", 1)).
+			Info("dup", "user", "record", slog.Group("g", "b", 2), "id", 3)
+		assert.Contains(t, buf.String(), `"attributes":`+attributes+`}`, policy)
+	}
+
+	err := (&LogConfig{MangoConfig: &MangoConfig{CorrelationId: &CorrelationIdConfig{}, DuplicateKeys: "random"}, Out: &OutConfig{}}).Validate()
+	assert.ErrorContains(t, err, `mango.duplicate-keys: unknown policy "random"`)
 }
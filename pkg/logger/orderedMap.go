@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// OrderedMap is a json object keeping the insertion order of its keys, used for the log attributes
+// (nested groups being OrderedMaps as well) and by the encoders. The zero value is an empty map ready to use.
+type OrderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+// Set the value of the key, a new key being added last and an existing one keeping its position
+func (o *OrderedMap) Set(key string, value any) {
+	if o.values == nil {
+		o.values = make(map[string]any)
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Get the value of the key
+func (o *OrderedMap) Get(key string) (any, bool) {
+	if o == nil {
+		return nil, false
+	}
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// Delete the key
+func (o *OrderedMap) Delete(key string) {
+	if _, ok := o.Get(key); !ok {
+		return
+	}
+	delete(o.values, key)
+	o.keys = slices.DeleteFunc(o.keys, func(k string) bool { return k == key })
+}
+
+// Keys in insertion order
+func (o *OrderedMap) Keys() []string {
+	if o == nil {
+		return nil
+	}
+	return slices.Clone(o.keys)
+}
+
+// Len is the number of keys
+func (o *OrderedMap) Len() int {
+	if o == nil {
+		return 0
+	}
+	return len(o.keys)
+}
+
+// Map converts the OrderedMap to a plain map, nested OrderedMaps included
+func (o *OrderedMap) Map() map[string]interface{} {
+	result := make(map[string]interface{}, o.Len())
+	for _, key := range o.Keys() {
+		result[key] = plainValue(o.values[key])
+	}
+	return result
+}
+
+func plainValue(value any) any {
+	switch v := value.(type) {
+	case *OrderedMap:
+		return v.Map()
+	case []interface{}:
+		converted := make([]interface{}, len(v))
+		for i, item := range v {
+			converted[i] = plainValue(item)
+		}
+		return converted
+	}
+	return value
+}
+
+func (o *OrderedMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// UnmarshalJSON keeps the order of the keys, nested objects becoming OrderedMaps and numbers float64
+func (o *OrderedMap) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	value, err := decodeOrderedValue(decoder)
+	if err != nil {
+		return err
+	}
+	parsed, ok := value.(*OrderedMap)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal %s into an OrderedMap", data)
+	}
+	*o = *parsed
+	return nil
+}
+
+func decodeOrderedValue(decoder *json.Decoder) (any, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		object := &OrderedMap{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.Set(key.(string), value)
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrderedValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = decoder.Token()
+		return list, err
+	}
+	return token, nil
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// attributesOf builds an OrderedMap from key, value pairs
+func attributesOf(keysAndValues ...any) *OrderedMap {
+	attributes := &OrderedMap{}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		attributes.Set(keysAndValues[i].(string), keysAndValues[i+1])
+	}
+	return attributes
+}
+
+func TestOrderedMap(t *testing.T) {
+	var empty *OrderedMap
+	assert.Equal(t, 0, empty.Len())
+	assert.Empty(t, empty.Keys())
+	assert.Equal(t, map[string]interface{}{}, empty.Map())
+
+	attributes := attributesOf("b", 1, "a", attributesOf("z", true, "y", []interface{}{attributesOf("k", "v")}), "c", nil)
+	attributes.Set("b", 2)
+	assert.Equal(t, []string{"b", "a", "c"}, attributes.Keys())
+	value, ok := attributes.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 2, value)
+
+	attributes.Delete("c")
+	attributes.Delete("missing")
+	_, ok = attributes.Get("c")
+	assert.False(t, ok)
+	assert.Equal(t, 2, attributes.Len())
+
+	marshalled, err := json.Marshal(attributes)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"b":2,"a":{"z":true,"y":[{"k":"v"}]}}`, string(marshalled))
+	assert.Equal(t, map[string]interface{}{
+		"b": 2,
+		"a": map[string]interface{}{"z": true, "y": []interface{}{map[string]interface{}{"k": "v"}}},
+	}, attributes.Map())
+
+	var decoded OrderedMap
+	assert.NoError(t, json.Unmarshal(marshalled, &decoded))
+	remarshalled, err := json.Marshal(&decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, string(marshalled), string(remarshalled))
+	assert.Error(t, json.Unmarshal([]byte(`[1]`), &decoded))
+}
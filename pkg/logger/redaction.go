@@ -0,0 +1,407 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaskStrategy is how a sensitive value is masked
+type MaskStrategy string
+
+const (
+	// MaskFull replaces the whole value with RedactedValue (default)
+	MaskFull MaskStrategy = "full"
+
+	// MaskPartial keeps the last 4 characters, e.g. ************1111 (values shorter than 8 characters are fully masked)
+	MaskPartial MaskStrategy = "partial"
+
+	// MaskHash replaces the value with a short HMAC-SHA256 digest keyed with RedactionConfig.HashKey (hmac-sha256:<16 hex>),
+	// so equal values can be correlated without being brute-forced back from the logs
+	MaskHash MaskStrategy = "hash"
+)
+
+// RedactedValue replaces the values masked with MaskFull
+const RedactedValue = "[REDACTED]"
+
+// Built-in value patterns of RedactionConfig.Patterns
+const (
+	// RedactPAN masks card numbers (13 to 19 digits, optionally separated by spaces or dashes, passing the Luhn check)
+	RedactPAN = "pan"
+
+	// RedactEmail masks email addresses
+	RedactEmail = "email"
+
+	// RedactJWT masks JSON Web Tokens
+	RedactJWT = "jwt"
+
+	// RedactBearer masks the token of "Bearer <token>" authorization values
+	RedactBearer = "bearer"
+)
+
+// DefaultRedactedKeys are the attribute key patterns used when RedactionConfig.Keys is not set
+var DefaultRedactedKeys = []string{"password", "passwd", "secret", "token", "authorization", "api[-_.]?key", "cookie", "credential"}
+
+// redactionPatterns are the built-in value patterns, the first capture group (if any) being the part masked
+var redactionPatterns = map[string]valuePattern{
+	RedactPAN:    {re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhn},
+	RedactEmail:  {re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	RedactJWT:    {re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)},
+	RedactBearer: {re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]+=*)`)},
+}
+
+// redactionPatternNames lists the built-in patterns in the order they are applied
+var redactionPatternNames = []string{RedactBearer, RedactJWT, RedactPAN, RedactEmail}
+
+// valuePattern finds the sensitive parts of string values
+type valuePattern struct {
+	re *regexp.Regexp
+
+	// valid filters out the matches that are not sensitive (e.g. digits failing the Luhn check)
+	valid func(match string) bool
+}
+
+// compileKeyPattern compiles a RedactionConfig.Keys entry, matched case insensitively against attribute keys
+func compileKeyPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+// compileValuePattern resolves a RedactionConfig.Patterns entry, either a built-in pattern name or a regular expression
+func compileValuePattern(pattern string) (valuePattern, error) {
+	if builtIn, ok := redactionPatterns[strings.ToLower(pattern)]; ok {
+		return builtIn, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return valuePattern{}, err
+	}
+	return valuePattern{re: re}, nil
+}
+
+// redactor masks the sensitive attributes, message and context fields of the logs
+// A nil redactor leaves the logs untouched
+type redactor struct {
+	keys     []*regexp.Regexp
+	patterns []valuePattern
+	strategy MaskStrategy
+	hashKey  []byte
+}
+
+// newRedactor compiles the key and value patterns, skipping the invalid ones, nil when redaction is disabled
+func newRedactor(config *RedactionConfig) *redactor {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	r := &redactor{strategy: config.Strategy}
+	if config.HashKey != "" {
+		r.hashKey = []byte(config.HashKey)
+	} else if r.strategy == MaskHash {
+		// an unkeyed digest of a card number or pin is as good as the value
+		r.strategy = MaskFull
+	}
+	keys := config.Keys
+	if keys == nil {
+		keys = DefaultRedactedKeys
+	}
+	for _, key := range keys {
+		if re, err := compileKeyPattern(key); err == nil {
+			r.keys = append(r.keys, re)
+		}
+	}
+	patterns := config.Patterns
+	if patterns == nil {
+		patterns = redactionPatternNames
+	}
+	for _, pattern := range patterns {
+		if compiled, err := compileValuePattern(pattern); err == nil {
+			r.patterns = append(r.patterns, compiled)
+		}
+	}
+	return r
+}
+
+// redact masks the log in place, the context fields and attribute maps being copied rather than modified as they may be shared with the caller
+func (r *redactor) redact(log *StructuredLog) {
+	if r == nil {
+		return
+	}
+	if message, ok := log.Message.(string); ok {
+		log.Message = r.redactString(message)
+	}
+	log.Stack = r.redactString(log.Stack)
+	if log.Fields != nil {
+		fields := make(map[string]string, len(log.Fields))
+		for name, value := range log.Fields {
+			if r.sensitiveKey(name) {
+				fields[name] = r.maskString(value)
+			} else {
+				fields[name] = r.redactString(value)
+			}
+		}
+		log.Fields = fields
+	}
+	if log.Attributes != nil {
+		log.Attributes = r.redactAttributes(log.Attributes)
+	}
+}
+
+func (r *redactor) sensitiveKey(key string) bool {
+	for _, re := range r.keys {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) redactMap(attributes map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		if r.sensitiveKey(key) {
+			redacted[key] = r.mask(value)
+			continue
+		}
+		redacted[key] = r.redactValue(value)
+	}
+	return redacted
+}
+
+func (r *redactor) redactAttributes(attributes *OrderedMap) *OrderedMap {
+	redacted := &OrderedMap{}
+	for _, key := range attributes.Keys() {
+		value, _ := attributes.Get(key)
+		if r.sensitiveKey(key) {
+			redacted.Set(key, r.mask(value))
+			continue
+		}
+		redacted.Set(key, r.redactValue(value))
+	}
+	return redacted
+}
+
+func (r *redactor) redactValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case *OrderedMap:
+		return r.redactAttributes(v)
+	case map[string]interface{}:
+		return r.redactMap(v)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.redactValue(item)
+		}
+		return redacted
+	case []string:
+		redacted := make([]string, len(v))
+		for i, item := range v {
+			redacted[i] = r.redactString(item)
+		}
+		return redacted
+	case json.Number:
+		return r.redactNumber(value, string(v))
+	case hashedSecret:
+		return r.hashSecret(v)
+	case nil, bool:
+		return value
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return r.redactNumber(value, fmt.Sprint(value))
+	case reflect.Float32, reflect.Float64:
+		return r.redactNumber(value, strconv.FormatFloat(reflect.ValueOf(value).Float(), 'f', -1, 64))
+	}
+	return r.redactJSON(value)
+}
+
+// redactNumber masks a number through its decimal form (e.g. a card number logged as an int64), keeping it as is when nothing matches
+func (r *redactor) redactNumber(value any, text string) any {
+	if redacted := r.redactString(text); redacted != text {
+		return redacted
+	}
+	return value
+}
+
+// redactJSON masks the other values (structs, typed maps and slices...) through their json form, keeping them as is when nothing is masked
+func (r *redactor) redactJSON(value any) any {
+	text, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return value
+	}
+	redacted := r.redactValue(decoded)
+	if reflect.DeepEqual(decoded, redacted) {
+		return value
+	}
+	return redacted
+}
+
+// mask the whole value of a sensitive key, partial masking only applying to strings and numbers
+func (r *redactor) mask(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case hashedSecret:
+		return r.hashSecret(v)
+	case string, fmt.Stringer, int, int64, uint64, float64, json.Number:
+		return r.maskString(fmt.Sprint(value))
+	}
+	if r.strategy == MaskHash {
+		if text, err := json.Marshal(value); err == nil {
+			return r.maskString(string(text))
+		}
+	}
+	return RedactedValue
+}
+
+// redactString masks the parts of the value matching the patterns
+func (r *redactor) redactString(value string) string {
+	for _, pattern := range r.patterns {
+		value = pattern.replace(value, r.maskString)
+	}
+	return value
+}
+
+// maskString applies the strategy of the redactor to the value
+func (r *redactor) maskString(value string) string {
+	if r.strategy == MaskHash {
+		return hashString(r.hashKey, value)
+	}
+	return maskString(r.strategy, value)
+}
+
+// hashSecret hashes a Secret masked with MaskHash, fully masking it without a hash key
+func (r *redactor) hashSecret(secret hashedSecret) string {
+	if r.hashKey == nil {
+		return RedactedValue
+	}
+	return hashString(r.hashKey, string(secret))
+}
+
+// replace the matches (or their first capture group) with their masked value
+func (p valuePattern) replace(value string, mask func(string) string) string {
+	matches := p.re.FindAllStringSubmatchIndex(value, -1)
+	if matches == nil {
+		return value
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if len(match) > 2 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		if p.valid != nil && !p.valid(value[start:end]) {
+			continue
+		}
+		b.WriteString(value[last:start])
+		b.WriteString(mask(value[start:end]))
+		last = end
+	}
+	b.WriteString(value[last:])
+	return b.String()
+}
+
+// maskString applies the strategy to the value, MaskHash needing a key it is applied as MaskFull
+func maskString(strategy MaskStrategy, value string) string {
+	if strategy == MaskPartial {
+		runes := []rune(value)
+		if len(runes) < 8 {
+			return RedactedValue
+		}
+		return strings.Repeat("*", len(runes)-4) + string(runes[len(runes)-4:])
+	}
+	return RedactedValue
+}
+
+// hashString is the short HMAC-SHA256 digest of the value
+func hashString(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// luhn checks the digits of a card number (ignoring separators)
+func luhn(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Secret is a string never written to the logs as is, e.g. slog.Any("password", logger.Secret(password))
+// It is masked with MaskFull, also when printed with fmt or marshalled (json, yaml, ...)
+type Secret string
+
+// LogValue masks the secret
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(RedactedValue)
+}
+
+// String masks the secret
+func (s Secret) String() string {
+	return RedactedValue
+}
+
+// MarshalText masks the secret
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(RedactedValue), nil
+}
+
+// Masked logs the secret with another strategy, e.g. slog.Any("card", logger.Secret(pan).Masked(logger.MaskPartial))
+// MaskHash uses RedactionConfig.HashKey, the secret being fully masked when redaction has no hash key
+func (s Secret) Masked(strategy MaskStrategy) slog.LogValuer {
+	return maskedSecret{value: string(s), strategy: strategy}
+}
+
+type maskedSecret struct {
+	value    string
+	strategy MaskStrategy
+}
+
+func (s maskedSecret) LogValue() slog.Value {
+	if s.strategy == MaskHash {
+		return slog.AnyValue(hashedSecret(s.value))
+	}
+	return slog.StringValue(maskString(s.strategy, s.value))
+}
+
+// hashedSecret is a Secret hashed by the redactor with its key, written as RedactedValue anywhere else
+type hashedSecret string
+
+func (s hashedSecret) String() string {
+	return RedactedValue
+}
+
+func (s hashedSecret) MarshalText() ([]byte, error) {
+	return []byte(RedactedValue), nil
+}
@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SamplingSummaryMessage is the message of the records reporting the suppressed records
+const SamplingSummaryMessage = "Log records suppressed by sampling and rate limiting"
+
+// samplingOperation is the OPERATION of the summary records
+const samplingOperation = "logSampling"
+
+// maxSampleKeys bounds the messages counted in a sampling interval, the others being counted as seen once
+const maxSampleKeys = 4096
+
+// sampleKey identifies the records sampled together
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+// sampler counts the records per message and level and holds the token buckets of the rate limited levels
+type sampler struct {
+	first      int
+	thereafter int
+	interval   time.Duration
+
+	// includeErrors also samples the records at ERROR and above
+	includeErrors bool
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[sampleKey]int
+	buckets     map[slog.Level]*tokenBucket
+	sampled     map[slog.Level]uint64
+	rateLimited map[slog.Level]uint64
+
+	suppressed atomic.Uint64
+
+	// summary writes the summary record, called by the summary worker and on close
+	summary func(sampled, rateLimited map[slog.Level]uint64)
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	now func() time.Time
+}
+
+// newSampler starts the summary worker, nil when sampling is disabled
+func newSampler(config *SamplingConfig, levels levelNames, summary func(sampled, rateLimited map[slog.Level]uint64)) *sampler {
+	if config == nil || !config.Enabled {
+		return nil
+	}
+	s := &sampler{
+		first:         config.First,
+		thereafter:    config.Thereafter,
+		interval:      parseDurationOr(config.Interval, DefaultSamplingInterval),
+		includeErrors: config.IncludeErrors,
+		counts:        make(map[sampleKey]int),
+		buckets:       make(map[slog.Level]*tokenBucket),
+		sampled:       make(map[slog.Level]uint64),
+		rateLimited:   make(map[slog.Level]uint64),
+		summary:       summary,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+		now:           time.Now,
+	}
+	for name, rate := range config.RateLimits {
+		if level, err := levels.parse(name); err == nil && rate > 0 {
+			s.buckets[level] = newTokenBucket(rate)
+		}
+	}
+	go s.work(parseDurationOr(config.SummaryInterval, DefaultSamplingSummaryInterval))
+	return s
+}
+
+func parseDurationOr(value, fallback string) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	d, _ := time.ParseDuration(fallback)
+	return d
+}
+
+// allow reports whether the record is logged, counting it as suppressed otherwise
+// Records are first sampled by message and level, then rate limited by level
+func (s *sampler) allow(level slog.Level, message string) bool {
+	if s == nil || level >= slog.LevelError && !s.includeErrors {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.first > 0 || s.thereafter > 0 {
+		if now.Sub(s.windowStart) >= s.interval {
+			s.windowStart = now
+			// a new map releases the memory of a busy interval
+			s.counts = make(map[sampleKey]int)
+		}
+		key := sampleKey{level: level, message: message}
+		count, ok := s.counts[key]
+		count++
+		if ok || len(s.counts) < maxSampleKeys {
+			s.counts[key] = count
+		}
+		if count > s.first && (s.thereafter <= 0 || (count-s.first)%s.thereafter != 0) {
+			s.sampled[level]++
+			s.suppressed.Add(1)
+			return false
+		}
+	}
+	if bucket, ok := s.buckets[level]; ok && !bucket.take(now) {
+		s.rateLimited[level]++
+		s.suppressed.Add(1)
+		return false
+	}
+	return true
+}
+
+func (s *sampler) work(summaryInterval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(summaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.report()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// report hands the suppressed counts since the last report over to the summary, if any
+func (s *sampler) report() {
+	s.mu.Lock()
+	if len(s.sampled) == 0 && len(s.rateLimited) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	sampled, rateLimited := s.sampled, s.rateLimited
+	s.sampled, s.rateLimited = make(map[slog.Level]uint64), make(map[slog.Level]uint64)
+	s.mu.Unlock()
+	s.summary(sampled, rateLimited)
+}
+
+// close stops the summary worker and reports the last suppressed counts
+func (s *sampler) close() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		s.report()
+	})
+}
+
+// tokenBucket allows rate records per second, with bursts of up to rate records
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate)}
+}
+
+func (b *tokenBucket) take(now time.Time) bool {
+	if !b.last.IsZero() {
+		b.tokens = min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
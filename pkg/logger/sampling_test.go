@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestSampler creates a sampler on a fake clock, returning the summaries it reports
+func newTestSampler(t *testing.T, config *SamplingConfig) (*sampler, *time.Time, *[]map[slog.Level]uint64) {
+	var summaries []map[slog.Level]uint64
+	s := newSampler(config, newLevelNames(nil), func(sampled, rateLimited map[slog.Level]uint64) {
+		summaries = append(summaries, sampled, rateLimited)
+	})
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	t.Cleanup(s.close)
+	return s, &now, &summaries
+}
+
+func allowed(s *sampler, level slog.Level, message string, n int) int {
+	count := 0
+	for range n {
+		if s.allow(level, message) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSampler_FirstThereafter(t *testing.T) {
+	s, now, summaries := newTestSampler(t, &SamplingConfig{Enabled: true, Interval: "1s", First: 3, Thereafter: 10})
+
+	// 3 first, then the 13th, 23rd...
+	assert.Equal(t, 5, allowed(s, slog.LevelInfo, "cache miss", 25))
+	// keyed by message and level
+	assert.Equal(t, 3, allowed(s, slog.LevelInfo, "cache hit", 3))
+	assert.Equal(t, 3, allowed(s, slog.LevelDebug, "cache miss", 3))
+
+	// a new interval starts over
+	*now = now.Add(time.Second)
+	assert.Equal(t, 3, allowed(s, slog.LevelInfo, "cache miss", 4))
+	assert.Equal(t, uint64(21), s.suppressed.Load())
+
+	s.report()
+	assert.Equal(t, []map[slog.Level]uint64{{slog.LevelInfo: 21}, {}}, *summaries)
+	// nothing suppressed since the last summary
+	s.report()
+	assert.Len(t, *summaries, 2)
+
+	// thereafter 0 drops everything after the first records
+	s, _, _ = newTestSampler(t, &SamplingConfig{Enabled: true, Interval: "1s", First: 2})
+	assert.Equal(t, 2, allowed(s, slog.LevelInfo, "cache miss", 10))
+}
+
+func TestSampler_RateLimits(t *testing.T) {
+	s, now, summaries := newTestSampler(t, &SamplingConfig{Enabled: true, RateLimits: map[string]int{"DEBUG": 10, "info": 2}})
+
+	assert.Equal(t, 10, allowed(s, slog.LevelDebug, "a", 15))
+	assert.Equal(t, 2, allowed(s, slog.LevelInfo, "b", 5))
+	assert.Equal(t, 7, allowed(s, slog.LevelWarn, "c", 7))
+
+	// the buckets refill with time, up to the burst
+	*now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, 5, allowed(s, slog.LevelDebug, "a", 15))
+	*now = now.Add(time.Hour)
+	assert.Equal(t, 2, allowed(s, slog.LevelInfo, "b", 5))
+
+	s.close()
+	assert.Equal(t, []map[slog.Level]uint64{{}, {slog.LevelDebug: 15, slog.LevelInfo: 6}}, *summaries)
+}
+
+func TestSampler_Errors(t *testing.T) {
+	config := &SamplingConfig{Enabled: true, Interval: "1s", First: 1, RateLimits: map[string]int{"ERROR": 1}}
+	s, _, _ := newTestSampler(t, config)
+	assert.Equal(t, 5, allowed(s, slog.LevelError, "failed", 5))
+	assert.Equal(t, 1, allowed(s, slog.LevelWarn, "failed", 5))
+
+	config.IncludeErrors = true
+	s, _, _ = newTestSampler(t, config)
+	assert.Equal(t, 1, allowed(s, slog.LevelError, "failed", 5))
+
+	// disabled
+	assert.Nil(t, newSampler(&SamplingConfig{First: 1}, newLevelNames(nil), nil))
+	assert.True(t, (*sampler)(nil).allow(slog.LevelDebug, "any"))
+}
+
+func TestSampler_MaxKeys(t *testing.T) {
+	s, now, _ := newTestSampler(t, &SamplingConfig{Enabled: true, Interval: "1s", First: 1})
+
+	assert.Equal(t, 1, allowed(s, slog.LevelInfo, "repeated", 1))
+	for i := range maxSampleKeys + 100 {
+		s.allow(slog.LevelInfo, "unique "+strconv.Itoa(i))
+	}
+	assert.Len(t, s.counts, maxSampleKeys)
+	// the messages counted before the cap are still sampled
+	assert.Equal(t, 0, allowed(s, slog.LevelInfo, "repeated", 5))
+
+	// and the next interval starts from an empty map
+	*now = now.Add(time.Second)
+	assert.Equal(t, 1, allowed(s, slog.LevelInfo, "repeated", 5))
+	assert.Len(t, s.counts, 1)
+}
+
+func TestMangoLogger_SamplingAfterFiltering(t *testing.T) {
+	config := &LogConfig{Out: &OutConfig{Enabled: true, Sampling: &SamplingConfig{Enabled: true, First: 2}}}
+	logger, buf := newBufferedLogger(t, config, slog.LevelInfo, nil)
+	logger.SetOperationLevel("quiet", slog.LevelWarn)
+	l := slog.New(logger)
+
+	// records filtered by the level of their operation or output don't use up the sampling budget
+	quiet := WithOperation(context.Background(), "quiet")
+	for range 5 {
+		l.InfoContext(quiet, "hot path")
+		l.Debug("hot path")
+	}
+	l.Info("hot path")
+	l.Info("hot path")
+	assert.Equal(t, 2, strings.Count(buf.String(), `"hot path"`))
+	assert.Equal(t, uint64(0), logger.Suppressed())
+}
+
+func TestMangoLogger_SamplingFlightRecorder(t *testing.T) {
+	config := &LogConfig{Out: &OutConfig{
+		Enabled:        true,
+		Sampling:       &SamplingConfig{Enabled: true, First: 1},
+		FlightRecorder: &FlightRecorderConfig{Enabled: true},
+	}}
+	logger, buf := newBufferedLogger(t, config, slog.LevelInfo, nil)
+	l := slog.New(logger)
+
+	// the records suppressed by sampling are kept by the flight recorder, written ahead of the error
+	ctx := WithCorrelationID(context.Background(), "corr-1")
+	for range 3 {
+		l.InfoContext(ctx, "step")
+	}
+	assert.Equal(t, 1, strings.Count(buf.String(), `"step"`))
+	l.ErrorContext(ctx, "failed")
+	assert.Equal(t, 3, strings.Count(buf.String(), `"step"`))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"failed"`))
+	assert.Equal(t, uint64(2), logger.Suppressed())
+}
+
+func TestMangoLogger_Sampling(t *testing.T) {
+	logger := newTestLogger(false, false, false, true)
+	logger.Config.Out.Sampling = &SamplingConfig{Enabled: true, First: 2, RateLimits: map[string]int{"DEBUG": 1}}
+	logger = NewMangoLogger(logger.Config)
+	var buf bytes.Buffer
+	logger.AddAppender(NewWriterAppender(&buf, slog.LevelDebug, nil))
+
+	for range 5 {
+		handleMessage(t, logger, slog.LevelInfo, "hot path")
+		handleMessage(t, logger, slog.LevelDebug, "details")
+		handleMessage(t, logger, slog.LevelError, "failed")
+	}
+	assert.Equal(t, 2, strings.Count(buf.String(), `"hot path"`))
+	assert.Equal(t, 1, strings.Count(buf.String(), `"details"`))
+	assert.Equal(t, 5, strings.Count(buf.String(), `"failed"`))
+	assert.Equal(t, uint64(7), logger.Suppressed())
+
+	// the last summary is written on close
+	buf.Reset()
+	assert.NoError(t, logger.Close(context.Background()))
+	var summary StructuredLog
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &summary))
+	assert.Equal(t, SamplingSummaryMessage, summary.Message)
+	assert.Equal(t, "WARN", summary.LevelName)
+	assert.Equal(t, PerformanceType, summary.Type)
+	assert.Equal(t, "logSampling", summary.Operation)
+	assert.Equal(t, map[string]interface{}{
+		"suppressed":  float64(7),
+		"sampled":     float64(6),
+		"rateLimited": float64(1),
+		"levels":      map[string]interface{}{"DEBUG": float64(4), "INFO": float64(3)},
+	}, summary.Attributes.Map())
+}
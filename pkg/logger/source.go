@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// maxStackDepth is the maximum number of frames of the captured stacks
+const maxStackDepth = 64
+
+// recordSource is the file, line and function of the logging call from the record PC
+func recordSource(pc uintptr) *slog.Source {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}
+}
+
+// callerStack renders the current stack from the logging call (the frame of the record PC) in the runtime/debug.Stack style:
+//
+//	main.checkout
+//		/app/main.go:42
+//
+// The frames of the logger are kept when the PC is unknown (0) or not found in the stack
+func callerStack(pc uintptr) string {
+	pcs := make([]uintptr, maxStackDepth)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	if i := slices.Index(pcs, pc); pc != 0 && i >= 0 {
+		pcs = pcs[i:]
+	}
+
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMangoLogger_AddSource(t *testing.T) {
+	mangoLogger, buf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{AddSource: true}}, slog.LevelDebug, nil)
+	logger := slog.New(mangoLogger)
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("with source")
+
+	var log map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	source := log["source"].(map[string]interface{})
+	assert.True(t, strings.HasSuffix(source["file"].(string), "source_test.go"))
+	assert.Equal(t, float64(line+1), source["line"])
+	assert.Equal(t, "github.com/bitstep-ie/mango-go/pkg/logger.TestMangoLogger_AddSource", source["function"])
+	assert.NotContains(t, log, "stack")
+
+	// no source by default
+	mangoLogger, buf = newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{}}, slog.LevelDebug, nil)
+	logger = slog.New(mangoLogger)
+	logger.Info("without source")
+	assert.NotContains(t, buf.String(), `"source"`)
+}
+
+func TestMangoLogger_ErrorStack(t *testing.T) {
+	mangoLogger, buf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{ErrorStack: true}}, slog.LevelDebug, nil)
+	logger := slog.New(mangoLogger)
+	logger.Warn("no stack")
+	assert.NotContains(t, buf.String(), `"stack"`)
+	buf.Reset()
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Error("failed")
+	var log StructuredLog
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	lines := strings.Split(log.Stack, "\n")
+	assert.Equal(t, "github.com/bitstep-ie/mango-go/pkg/logger.TestMangoLogger_ErrorStack", lines[0])
+	assert.Contains(t, lines[1], fmt.Sprintf("source_test.go:%d", line+1))
+}
+
+type cyclicError struct{}
+
+func (e *cyclicError) Error() string { return "cycle" }
+func (e *cyclicError) Unwrap() error { return e }
+
+func TestErrorValue(t *testing.T) {
+	pathErr := &fs.PathError{Op: "open", Path: "config.yaml", Err: fs.ErrNotExist}
+	wrapped := fmt.Errorf("loading config: %w", pathErr)
+	joined := errors.Join(wrapped, errors.New("second"))
+
+	assert.Equal(t, map[string]interface{}{
+		"message": joined.Error(),
+		"type":    "*errors.joinError",
+		"errors": []interface{}{
+			map[string]interface{}{
+				"message": "loading config: open config.yaml: file does not exist",
+				"type":    "*fmt.wrapError",
+				"cause": map[string]interface{}{
+					"message": "open config.yaml: file does not exist",
+					"type":    "*fs.PathError",
+					"cause":   map[string]interface{}{"message": "file does not exist", "type": "*errors.errorString"},
+				},
+			},
+			map[string]interface{}{"message": "second", "type": "*errors.errorString"},
+		},
+	}, ErrorValue(joined))
+
+	// cyclic chains are cut
+	depth := 0
+	for value := ErrorValue(&cyclicError{}); value != nil; depth++ {
+		value, _ = value["cause"].(map[string]interface{})
+	}
+	assert.Equal(t, maxErrorDepth+1, depth)
+}
+
+func TestMangoLogger_ErrorAttributes(t *testing.T) {
+	mangoLogger, buf := newBufferedLogger(t, &LogConfig{MangoConfig: &MangoConfig{}}, slog.LevelDebug, nil)
+	logger := slog.New(mangoLogger)
+	logger.Error("failed", "err", fmt.Errorf("saving cart: %w", errors.New("timeout")), slog.Group("retry", "last", errors.New("refused")))
+
+	var log map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	assert.Equal(t, map[string]interface{}{
+		"err": map[string]interface{}{
+			"message": "saving cart: timeout",
+			"type":    "*fmt.wrapError",
+			"cause":   map[string]interface{}{"message": "timeout", "type": "*errors.errorString"},
+		},
+		"retry": map[string]interface{}{
+			"last": map[string]interface{}{"message": "refused", "type": "*errors.errorString"},
+		},
+	}, log["attributes"])
+}
+
+func TestNewEncoder_SourceFields(t *testing.T) {
+	log := newEncodingTestLog()
+	log.Attributes = nil
+	log.Source = &slog.Source{Function: "main.run", File: "/app/main.go", Line: 42}
+	log.Stack = "main.run\n\t/app/main.go:42"
+
+	expected := map[Encoding]string{
+		EncodingLogfmt: `lvl=WARN message="cart created" source.function=main.run source.file=/app/main.go source.line=42 stack="main.run\n\t/app/main.go:42"`,
+		EncodingECS: `"message":"cart created","ecs.version":"` + ECSVersion + `","log.origin.function":"main.run","log.origin.file.name":"/app/main.go",` +
+			`"log.origin.file.line":42,"error.stack_trace":"main.run\n\t/app/main.go:42"`,
+		EncodingOTel: `"log.record.uid":"id-1","code.function":"main.run","code.filepath":"/app/main.go","code.lineno":42,` +
+			`"exception.stacktrace":"main.run\n\t/app/main.go:42"}`,
+	}
+	for encoding, fragment := range expected {
+		formatter, err := NewEncoder(encoding, map[string]string{"level": "lvl"})
+		assert.NoError(t, err)
+		result, err := formatter.Format(log)
+		assert.NoError(t, err)
+		assert.Contains(t, string(result), fragment, encoding)
+	}
+
+	result, err := JSONFormatter.Format(log)
+	assert.NoError(t, err)
+	assert.Contains(t, string(result), `"message":"cart created","source":{"function":"main.run","file":"/app/main.go","line":42},"stack":"main.run\n\t/app/main.go:42","attributes":null`)
+
+	console, _ := NewConsoleFormatter(false).Format(log)
+	assert.Contains(t, string(console), "source=main.go:42\n    stack:\n      main.run\n      \t/app/main.go:42")
+}
@@ -1,6 +1,12 @@
 package logger
 
-import "log/slog"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+)
 
 // StructuredLog is the structure of every log entry (output)
 type StructuredLog struct {
@@ -20,24 +26,144 @@ type StructuredLog struct {
 	// Correlationid from the caller or self generated allowing to relate different systems around one
 	Correlationid string `json:"correlationid"`
 
+	// TraceId is the W3C trace-id of the OpenTelemetry span or traceparent of the context, omitted when there is none
+	TraceId string `json:"traceId,omitempty"`
+
+	// SpanId is the W3C span-id (parent-id) of the context, omitted when there is no trace
+	SpanId string `json:"spanId,omitempty"`
+
+	// TraceFlags are the W3C trace-flags of the context (01 when sampled), omitted when there is no trace
+	TraceFlags string `json:"traceFlags,omitempty"`
+
+	// Fields are the custom context fields of MangoConfig.ContextFields, emitted as top-level fields (sorted by name, before level)
+	Fields map[string]string `json:"-"`
+
 	// LogId is a unique identifier for each log entry - Helps in referring to logs when searching
 	LogId string `json:"logId"`
 
-	// Level of the log entry (slog.Debug, slog.Info, slog.Warn, slog.Error)
-	Level slog.Level `json:"level"`
+	// Level of the log entry (slog.Debug, slog.Info, slog.Warn, slog.Error or any custom slog.Level)
+	Level slog.Level `json:"-"`
+
+	// LevelName is the output name of Level (e.g. INFO, or TRACE when configured in MangoConfig.LevelNames)
+	LevelName string `json:"level"`
 
 	// Message is the actual message of the log entry
 	Message any `json:"message"`
 
-	// Attributes set with slog or on the logger
-	Attributes map[string]interface{} `json:"attributes"`
+	// Source is the file, line and function of the logging call, set with MangoConfig.AddSource
+	Source *slog.Source `json:"source,omitempty"`
+
+	// Stack of the logging call for ERROR (and above) logs, set with MangoConfig.ErrorStack
+	Stack string `json:"stack,omitempty"`
+
+	// Attributes set on the logger then with slog, in that order - Attributes.Map() gives them as a plain map
+	Attributes *OrderedMap `json:"attributes"`
+}
+
+// MarshalJSON renders the log with the custom context fields as top-level fields
+func (l StructuredLog) MarshalJSON() ([]byte, error) {
+	type plain StructuredLog
+	if len(l.Fields) == 0 {
+		return json.Marshal(plain(l))
+	}
+	root := &OrderedMap{}
+	for _, field := range l.fields() {
+		if field.omitted() {
+			continue
+		}
+		root.Set(field.name, field.value)
+	}
+	root.Set("attributes", l.Attributes)
+	return json.Marshal(root)
+}
+
+// UnmarshalJSON reads a log written with MarshalJSON (mango json), the top-level fields that are not StructuredLog ones going to Fields
+// Level is parsed back from the level name (e.g. INFO, ERROR+4), custom level names (MangoConfig.LevelNames) being unknown here are left at INFO
+func (l *StructuredLog) UnmarshalJSON(data []byte) error {
+	type plain StructuredLog
+	if err := json.Unmarshal(data, (*plain)(l)); err != nil {
+		return err
+	}
+	l.Level, _ = newLevelNames(nil).parse(l.LevelName)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for name, raw := range fields {
+		var value string
+		if slices.Contains(standardFieldNames, name) || json.Unmarshal(raw, &value) != nil {
+			continue
+		}
+		if l.Fields == nil {
+			l.Fields = make(map[string]string)
+		}
+		l.Fields[name] = value
+	}
+	return nil
 }
 
 // Helper function to convert []slog.Attr to a map[string]interface{}
+// It is ToAttributes with DuplicateLastWins, as plain maps
 func ToMap(attrs []slog.Attr) map[string]interface{} {
-	result := make(map[string]interface{})
-	for _, attr := range attrs {
-		result[attr.Key] = attr.Value.Any()
+	return ToAttributes(attrs, DuplicateLastWins).Map()
+}
+
+// ToAttributes converts []slog.Attr to the log attributes, keeping their order
+// Groups become nested OrderedMaps (inlined when the group key is empty) and groups sharing a key are combined,
+// empty attrs and empty groups are dropped and the other duplicate keys are resolved with the policy.
+// The values are resolved and converted with AttrValue
+func ToAttributes(attrs []slog.Attr, policy DuplicateKeyPolicy) *OrderedMap {
+	return toAttributes(mergeAttrs(nil, attrs, policy), policy)
+}
+
+// toAttributes converts attrs already merged with mergeAttrs
+func toAttributes(merged []slog.Attr, policy DuplicateKeyPolicy) *OrderedMap {
+	result := &OrderedMap{}
+	for _, attr := range merged {
+		if attr.Value.Kind() != slog.KindGroup {
+			result.Set(attr.Key, AttrValue(attr.Value))
+			continue
+		}
+		if group := ToAttributes(attr.Value.Group(), policy); group.Len() > 0 {
+			result.Set(attr.Key, group)
+		}
 	}
 	return result
 }
+
+// maxErrorDepth bounds the unwrapping of errors, guarding against cyclic chains
+const maxErrorDepth = 32
+
+// ErrorValue is the log representation of an error: its message, its type and,
+// when it wraps other errors, the "cause" (Unwrap() error) or the "errors" (Unwrap() []error, e.g. errors.Join)
+func ErrorValue(err error) map[string]interface{} {
+	return errorValue(err, 0)
+}
+
+func errorValue(err error, depth int) map[string]interface{} {
+	value := map[string]interface{}{
+		"message": err.Error(),
+		"type":    fmt.Sprintf("%T", err),
+	}
+	if depth >= maxErrorDepth {
+		return value
+	}
+	switch wrapper := err.(type) {
+	case interface{ Unwrap() []error }:
+		var causes []interface{}
+		for _, cause := range wrapper.Unwrap() {
+			if cause != nil {
+				causes = append(causes, errorValue(cause, depth+1))
+			}
+		}
+		if len(causes) > 0 {
+			value["errors"] = causes
+		}
+	default:
+		if cause := errors.Unwrap(err); cause != nil {
+			value["cause"] = errorValue(cause, depth+1)
+		}
+	}
+	return value
+}
@@ -0,0 +1,220 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// This is to enable tests
+var (
+	syslogMinBackoff           = 100 * time.Millisecond
+	syslogMaxBackoff           = 30 * time.Second
+	syslogWarnings   io.Writer = os.Stdout
+)
+
+var errSyslogBackoff = errors.New("syslog connection unavailable, waiting before reconnecting")
+
+// ErrSyslogUnsupported is returned when the SyslogConfig asks for a transport not available on this platform
+var ErrSyslogUnsupported = errors.New("syslog transport not supported on this platform")
+
+// syslogFacilityCodes are the numerical facility codes of RFC 5424
+var syslogFacilityCodes = map[SyslogFacility]int{
+	SyslogFacilityKern:     0,
+	SyslogFacilityUser:     1,
+	SyslogFacilityMail:     2,
+	SyslogFacilityDaemon:   3,
+	SyslogFacilityAuth:     4,
+	SyslogFacilitySyslog:   5,
+	SyslogFacilityNews:     7,
+	SyslogFacilityUucp:     8,
+	SyslogFacilityCron:     9,
+	SyslogFacilityAuthpriv: 10,
+	SyslogFacilityFtp:      11,
+	SyslogFacilityLocal0:   16,
+	SyslogFacilityLocal1:   17,
+	SyslogFacilityLocal2:   18,
+	SyslogFacilityLocal3:   19,
+	SyslogFacilityLocal4:   20,
+	SyslogFacilityLocal5:   21,
+	SyslogFacilityLocal6:   22,
+	SyslogFacilityLocal7:   23,
+}
+
+// syslogSeverity maps the slog level to the numerical severity of RFC 5424
+// Custom levels fall into the closest severity at or below them, e.g. NOTICE (INFO+2) is notice and FATAL (ERROR+4) is critical
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return 7 // debug
+	case level == slog.LevelInfo:
+		return 6 // informational
+	case level < slog.LevelWarn:
+		return 5 // notice
+	case level < slog.LevelError:
+		return 4 // warning
+	case level < slog.LevelError+4:
+		return 3 // error
+	default:
+		return 2 // critical
+	}
+}
+
+// syslogWriter is a single connection to a syslog daemon for one facility and tag
+type syslogWriter interface {
+	write(log *StructuredLog, msg []byte) error
+	Close() error
+}
+
+// syslogAppender is the built-in Appender sending json to syslog as configured by SyslogConfig.
+// It keeps one long-lived connection per tag (the application of the log) for the configured facility.
+type syslogAppender struct {
+	config    *SyslogConfig
+	level     *outputLevel
+	formatter Formatter
+	mu        sync.Mutex
+	conns     map[string]*syslogConn
+}
+
+// Enabled when a facility is configured
+func (a *syslogAppender) Enabled() bool {
+	return a.config.Facility != ""
+}
+
+// Level is SyslogConfig.Level, DEBUG by default
+func (a *syslogAppender) Level() slog.Level {
+	return a.level.Level()
+}
+
+// Formatter encodes with SyslogConfig.Encoding, JSONFormatter when not set
+func (a *syslogAppender) Formatter() Formatter {
+	if a.formatter == nil {
+		return JSONFormatter
+	}
+	return a.formatter
+}
+
+func (a *syslogAppender) Append(log *StructuredLog, jsonOut []byte) error {
+	facility, ok := syslogFacilityCodes[a.config.Facility]
+	if !ok {
+		fmt.Println("Facility level not valid")
+		return fmt.Errorf("facility level not valid")
+	}
+
+	if err := checkSyslogTransport(a.config); err != nil {
+		fmt.Println("Syslog transport not supported")
+		return err
+	}
+
+	// outages are reported once by the connection, not for every record dropped
+	if err := a.conn(facility, log.Application).write(log, jsonOut); err != nil {
+		return fmt.Errorf("error writing to syslog: %w", err)
+	}
+	return nil
+}
+
+func (a *syslogAppender) conn(facility int, tag string) *syslogConn {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conns == nil {
+		a.conns = make(map[string]*syslogConn)
+	}
+	conn, ok := a.conns[tag]
+	if !ok {
+		config := a.config
+		conn = &syslogConn{tag: tag, dial: func() (syslogWriter, error) {
+			return dialSyslog(config, facility, tag)
+		}}
+		a.conns[tag] = conn
+	}
+	return conn
+}
+
+// Close all the syslog connections held by the appender
+func (a *syslogAppender) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var errs []error
+	for tag, conn := range a.conns {
+		errs = append(errs, conn.close())
+		delete(a.conns, tag)
+	}
+	return errors.Join(errs...)
+}
+
+// syslogConn is a syslog connection re-established on demand, backing off exponentially while the daemon is unreachable
+type syslogConn struct {
+	tag  string
+	dial func() (syslogWriter, error)
+
+	mu      sync.Mutex
+	writer  syslogWriter
+	backoff time.Duration
+	retryAt time.Time
+
+	// down is set from the first failed write until one succeeds again, dropped counting the records lost meanwhile
+	down    bool
+	dropped int
+}
+
+// write sends the record, warning once when the connection goes down and once when it recovers
+func (c *syslogConn) write(log *StructuredLog, msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := c.send(log, msg)
+	switch {
+	case err != nil && !c.down:
+		c.down, c.dropped = true, 1
+		_, _ = fmt.Fprintf(syslogWarnings, "Syslog unavailable for %s, dropping records until it recovers: %v\n", c.tag, err)
+	case err != nil:
+		c.dropped++
+	case c.down:
+		_, _ = fmt.Fprintf(syslogWarnings, "Syslog recovered for %s, %d records dropped\n", c.tag, c.dropped)
+		c.down, c.dropped = false, 0
+	}
+	return err
+}
+
+func (c *syslogConn) send(log *StructuredLog, msg []byte) error {
+	if c.writer == nil {
+		if time.Now().Before(c.retryAt) {
+			return errSyslogBackoff
+		}
+		writer, err := c.dial()
+		if err != nil {
+			c.scheduleRetry()
+			return err
+		}
+		c.writer = writer
+		c.backoff = 0
+	}
+
+	if err := c.writer.write(log, msg); err != nil {
+		_ = c.writer.Close()
+		c.writer = nil
+		c.scheduleRetry()
+		return err
+	}
+	return nil
+}
+
+func (c *syslogConn) scheduleRetry() {
+	c.backoff = min(max(c.backoff*2, syslogMinBackoff), syslogMaxBackoff)
+	c.retryAt = time.Now().Add(c.backoff)
+}
+
+func (c *syslogConn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writer == nil {
+		return nil
+	}
+	err := c.writer.Close()
+	c.writer = nil
+	return err
+}
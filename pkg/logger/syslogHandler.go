@@ -2,88 +2,51 @@
 
 package logger
 
-import (
-	"fmt"
-	"log/slog"
-	"log/syslog"
-)
+import "log/syslog"
 
-func (sl MangoLogger) handleSyslogOutput(log *StructuredLog, jsonOut []byte) error {
+// This is to enable tests
+var syslogDial = syslog.Dial
 
-	var severity = syslog.LOG_EMERG
-	switch log.Level {
-	case slog.LevelDebug:
-		severity = syslog.LOG_DEBUG
-	case slog.LevelInfo:
-		severity = syslog.LOG_INFO
-	case slog.LevelWarn:
-		severity = syslog.LOG_WARNING
-	case slog.LevelError:
-		severity = syslog.LOG_ERR
-	default:
-		fmt.Println("Record level not one of: debug, info, warn or error")
-	}
-	if severity == syslog.LOG_EMERG {
-		return fmt.Errorf("record level not one of: debug, info, warn or error")
-	}
+// checkSyslogTransport accepts every transport, the local ones are handled by log/syslog
+func checkSyslogTransport(*SyslogConfig) error {
+	return nil
+}
 
-	switch sl.Config.Out.Syslog.Facility {
-	case SyslogFacilityKern:
-		sl.Config.Out.Syslog.priority = syslog.LOG_KERN | severity
-	case SyslogFacilityUser:
-		sl.Config.Out.Syslog.priority = syslog.LOG_USER | severity
-	case SyslogFacilityMail:
-		sl.Config.Out.Syslog.priority = syslog.LOG_MAIL | severity
-	case SyslogFacilityDaemon:
-		sl.Config.Out.Syslog.priority = syslog.LOG_DAEMON | severity
-	case SyslogFacilityAuth:
-		sl.Config.Out.Syslog.priority = syslog.LOG_AUTH | severity
-	case SyslogFacilitySyslog:
-		sl.Config.Out.Syslog.priority = syslog.LOG_SYSLOG | severity
-	case SyslogFacilityNews:
-		sl.Config.Out.Syslog.priority = syslog.LOG_NEWS | severity
-	case SyslogFacilityUucp:
-		sl.Config.Out.Syslog.priority = syslog.LOG_UUCP | severity
-	case SyslogFacilityCron:
-		sl.Config.Out.Syslog.priority = syslog.LOG_CRON | severity
-	case SyslogFacilityAuthpriv:
-		sl.Config.Out.Syslog.priority = syslog.LOG_AUTHPRIV | severity
-	case SyslogFacilityFtp:
-		sl.Config.Out.Syslog.priority = syslog.LOG_FTP | severity
-	case SyslogFacilityLocal0:
-		sl.Config.Out.Syslog.priority = syslog.LOG_LOCAL0 | severity
-	case SyslogFacilityLocal1:
-		sl.Config.Out.Syslog.priority = syslog.LOG_LOCAL1 | severity
-	case SyslogFacilityLocal2:
-		sl.Config.Out.Syslog.priority = syslog.LOG_LOCAL2 | severity
-	case SyslogFacilityLocal3:
-		sl.Config.Out.Syslog.priority = syslog.LOG_LOCAL3 | severity
-	case SyslogFacilityLocal4:
-		sl.Config.Out.Syslog.priority = syslog.LOG_LOCAL4 | severity
-	case SyslogFacilityLocal5:
-		sl.Config.Out.Syslog.priority = syslog.LOG_LOCAL5 | severity
-	case SyslogFacilityLocal6:
-		sl.Config.Out.Syslog.priority = syslog.LOG_LOCAL6 | severity
-	case SyslogFacilityLocal7:
-		sl.Config.Out.Syslog.priority = syslog.LOG_LOCAL7 | severity
-	default:
-		fmt.Println("Facility level not valid")
-		return fmt.Errorf("facility level not valid")
+// dialSyslog connects to a remote collector for the network transports, or to the local daemon through log/syslog otherwise
+func dialSyslog(config *SyslogConfig, facility int, tag string) (syslogWriter, error) {
+	if isNetworkSyslog(config.Network) {
+		return dialNetworkSyslog(config, facility, tag)
 	}
-
-	syslogWriter, err := syslog.New(syslog.Priority(sl.Config.Out.Syslog.priority), log.Application)
+	writer, err := syslogDial(config.Network, config.Address, syslog.Priority(facility<<3), tag)
 	if err != nil {
-		fmt.Println("Error writing to syslog")
-		return fmt.Errorf("error writing to syslog: %w", err)
+		return nil, err
 	}
+	return &localSyslogWriter{writer: writer}, nil
+}
+
+// localSyslogWriter writes to the local syslog daemon, log/syslog already redials once on failure
+type localSyslogWriter struct {
+	writer *syslog.Writer
+}
 
-	defer func(syslogWriter *syslog.Writer) {
-		err := syslogWriter.Close()
-		if err != nil {
-			_ = fmt.Errorf("failed to close syslog writer %w", err)
-		}
-	}(syslogWriter)
+// write uses the writer's facility with the severity derived from the slog level
+func (w *localSyslogWriter) write(log *StructuredLog, msg []byte) error {
+	switch syslogSeverity(log.Level) {
+	case 7:
+		return w.writer.Debug(string(msg))
+	case 6:
+		return w.writer.Info(string(msg))
+	case 5:
+		return w.writer.Notice(string(msg))
+	case 4:
+		return w.writer.Warning(string(msg))
+	case 3:
+		return w.writer.Err(string(msg))
+	default:
+		return w.writer.Crit(string(msg))
+	}
+}
 
-	_, err = syslogWriter.Write(jsonOut)
-	return err
+func (w *localSyslogWriter) Close() error {
+	return w.writer.Close()
 }
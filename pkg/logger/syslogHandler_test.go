@@ -4,125 +4,206 @@ package logger
 
 import (
 	"errors"
-	"github.com/stretchr/testify/assert"
+	"fmt"
 	"log/slog"
 	"log/syslog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
-// mockSyslogWriter implements io.Writer and simulates a syslog.Writer
-type mockSyslogWriter struct {
-	writeErr error
-	closeErr error
-	written  []byte
+// syslogListener is a local unixgram stand-in for the syslog daemon
+type syslogListener struct {
+	path string
+	conn *net.UnixConn
 }
 
-func (m *mockSyslogWriter) Write(p []byte) (int, error) {
-	if m.writeErr != nil {
-		return 0, m.writeErr
-	}
-	m.written = append(m.written, p...)
-	return len(p), nil
+func newSyslogListener(t *testing.T) *syslogListener {
+	// unix socket paths are limited in length, t.TempDir() can be too long
+	dir, err := os.MkdirTemp("", "syslog")
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	l := &syslogListener{path: filepath.Join(dir, "log.sock")}
+	l.listen(t)
+	t.Cleanup(func() { _ = l.conn.Close() })
+	return l
 }
 
-func (m *mockSyslogWriter) Close() error {
-	return m.closeErr
+func (l *syslogListener) listen(t *testing.T) {
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: l.path, Net: "unixgram"})
+	assert.NoError(t, err)
+	l.conn = conn
 }
 
-// overrideable function for syslog.New to make testing easier
-var syslogNew = func(p syslog.Priority, tag string) (*syslog.Writer, error) {
-	return syslog.New(p, tag)
+func (l *syslogListener) stop() {
+	_ = l.conn.Close()
+	_ = os.Remove(l.path)
+}
+
+func (l *syslogListener) read(t *testing.T) string {
+	buf := make([]byte, 4096)
+	_ = l.conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := l.conn.Read(buf)
+	assert.NoError(t, err)
+	return string(buf[:n])
 }
 
-// createTestLogger constructs a MangoLogger with given facility
-func createTestLogger(facility SyslogFacility) MangoLogger {
-	return MangoLogger{
-		Config: &LogConfig{
-			Out: &OutConfig{
-				Syslog: &SyslogConfig{
-					Facility: facility,
-				},
-			},
+// createTestLogger constructs a syslog appender with given facility writing to the listener
+func createTestLogger(facility SyslogFacility, listener *syslogListener) *syslogAppender {
+	return &syslogAppender{
+		config: &SyslogConfig{
+			Facility: facility,
+			Network:  "unixgram",
+			Address:  listener.path,
 		},
 	}
 }
 
 func TestHandleSyslogOutput_ValidLevels(t *testing.T) {
-	facilities := []SyslogFacility{
-		SyslogFacilityKern,
-		SyslogFacilityUser,
-		SyslogFacilityMail,
-		SyslogFacilityDaemon,
-		SyslogFacilityAuth,
-		SyslogFacilitySyslog,
-		SyslogFacilityNews,
-		SyslogFacilityUucp,
-		SyslogFacilityCron,
-		SyslogFacilityAuthpriv,
-		SyslogFacilityFtp,
-		SyslogFacilityLocal0,
-		SyslogFacilityLocal1,
-		SyslogFacilityLocal2,
-		SyslogFacilityLocal3,
-		SyslogFacilityLocal4,
-		SyslogFacilityLocal5,
-		SyslogFacilityLocal6,
-		SyslogFacilityLocal7,
-	}
+	listener := newSyslogListener(t)
 
-	levels := []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+	severities := map[slog.Level]syslog.Priority{
+		slog.LevelDebug: syslog.LOG_DEBUG,
+		slog.LevelInfo:  syslog.LOG_INFO,
+		slog.LevelWarn:  syslog.LOG_WARNING,
+		slog.LevelError: syslog.LOG_ERR,
+	}
 
-	for _, f := range facilities {
-		for _, lvl := range levels {
-			logger := createTestLogger(f)
+	for f, facility := range syslogFacilityCodes {
+		for lvl, severity := range severities {
+			logger := createTestLogger(f, listener)
 			log := &StructuredLog{
 				Level:       lvl,
 				Application: "testApp",
 			}
-			err := logger.handleSyslogOutput(log, []byte(`{"msg":"hello"}`))
+			err := logger.Append(log, []byte(`{"msg":"hello"}`))
 			assert.NoError(t, err, "facility %v level %v", f, lvl)
+
+			received := listener.read(t)
+			assert.True(t, strings.HasPrefix(received, fmt.Sprintf("<%d>", syslog.Priority(facility<<3)|severity)), "facility %v level %v: %s", f, lvl, received)
+			assert.Contains(t, received, `testApp[`)
+			assert.Contains(t, received, `{"msg":"hello"}`)
+			assert.NoError(t, logger.Close())
 		}
 	}
 }
 
 func TestHandleSyslogOutput_InvalidFacility(t *testing.T) {
-	logger := createTestLogger("invalid_facility")
+	logger := createTestLogger("invalid_facility", newSyslogListener(t))
 	log := &StructuredLog{
 		Level:       slog.LevelInfo,
 		Application: "testApp",
 	}
 
-	err := logger.handleSyslogOutput(log, []byte(`{"msg":"oops"}`))
+	err := logger.Append(log, []byte(`{"msg":"oops"}`))
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "facility level not valid")
 }
 
-func TestHandleSyslogOutput_InvalidLevel(t *testing.T) {
-	logger := createTestLogger(SyslogFacilityUser)
-	log := &StructuredLog{
-		Level:       slog.Level(999), // invalid
-		Application: "testApp",
+func TestHandleSyslogOutput_CustomLevels(t *testing.T) {
+	listener := newSyslogListener(t)
+	logger := createTestLogger(SyslogFacilityUser, listener)
+
+	severities := map[slog.Level]syslog.Priority{
+		LevelTrace:          syslog.LOG_DEBUG,
+		LevelNotice:         syslog.LOG_NOTICE,
+		slog.LevelWarn + 1:  syslog.LOG_WARNING,
+		slog.LevelError + 2: syslog.LOG_ERR,
+		LevelFatal:          syslog.LOG_CRIT,
+		slog.Level(999):     syslog.LOG_CRIT,
+	}
+	for lvl, severity := range severities {
+		err := logger.Append(&StructuredLog{Level: lvl, Application: "testApp"}, []byte(`{"msg":"custom level"}`))
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(listener.read(t), fmt.Sprintf("<%d>", syslog.LOG_USER|severity)), "level %v", lvl)
 	}
-
-	err := logger.handleSyslogOutput(log, []byte(`{"msg":"bad level"}`))
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "record level not one of")
 }
 
 func TestHandleSyslogOutput_SyslogWriterCloseError(t *testing.T) {
-	logger := createTestLogger(SyslogFacilityUser)
-	log := &StructuredLog{
-		Level:       slog.LevelInfo,
-		Application: "testApp",
+	listener := newSyslogListener(t)
+	logger := createTestLogger(SyslogFacilityUser, listener)
+
+	for _, app := range []string{"app1", "app2", "app1"} {
+		err := logger.Append(&StructuredLog{Level: slog.LevelInfo, Application: app}, []byte(`{"msg":"close test"}`))
+		assert.NoError(t, err)
+		listener.read(t)
 	}
+	assert.Len(t, logger.conns, 2) // one long-lived connection per tag
+
+	assert.NoError(t, logger.Close())
+	assert.Empty(t, logger.conns)
+	assert.NoError(t, logger.Close())
+}
+
+func TestHandleSyslogOutput_Reconnect(t *testing.T) {
+	origMinBackoff := syslogMinBackoff
+	syslogMinBackoff = 10 * time.Millisecond
+	defer func() { syslogMinBackoff = origMinBackoff }()
 
-	origSyslogNew := syslogNew
-	syslogNew = func(p syslog.Priority, t string) (*syslog.Writer, error) {
-		_ = &mockSyslogWriter{closeErr: errors.New("close failed")}
-		return (*syslog.Writer)(nil), nil
+	var warnings strings.Builder
+	origWarnings := syslogWarnings
+	syslogWarnings = &warnings
+	defer func() { syslogWarnings = origWarnings }()
+
+	listener := newSyslogListener(t)
+	logger := createTestLogger(SyslogFacilityLocal0, listener)
+	log := &StructuredLog{Level: slog.LevelInfo, Application: "testApp"}
+
+	assert.NoError(t, logger.Append(log, []byte(`{"msg":"before"}`)))
+	assert.Contains(t, listener.read(t), "before")
+
+	listener.stop()
+	assert.Error(t, logger.Append(log, []byte(`{"msg":"lost"}`)))
+	for range 5 {
+		assert.ErrorIs(t, logger.Append(log, []byte(`{"msg":"lost"}`)), errSyslogBackoff)
 	}
-	defer func() { syslogNew = origSyslogNew }()
 
-	err := logger.handleSyslogOutput(log, []byte(`{"msg":"close test"}`))
+	listener.listen(t)
+	time.Sleep(2 * syslogMinBackoff)
+	assert.NoError(t, logger.Append(log, []byte(`{"msg":"after"}`)))
+	assert.Contains(t, listener.read(t), "after")
+
+	// one warning when the outage starts and one when it ends
+	lines := strings.Split(strings.TrimSpace(warnings.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "Syslog unavailable for testApp, dropping records until it recovers: ")
+	assert.Equal(t, "Syslog recovered for testApp, 6 records dropped", lines[1])
+}
+
+func TestHandleSyslogOutput_DialBackoff(t *testing.T) {
+	dials := 0
+	origDial := syslogDial
+	syslogDial = func(network, raddr string, priority syslog.Priority, tag string) (*syslog.Writer, error) {
+		dials++
+		return nil, errors.New("dial failed")
+	}
+	defer func() { syslogDial = origDial }()
+
+	logger := createTestLogger(SyslogFacilityUser, &syslogListener{})
+	log := &StructuredLog{Level: slog.LevelInfo, Application: "testApp"}
+
+	assert.ErrorContains(t, logger.Append(log, []byte(`{}`)), "dial failed")
+	assert.ErrorIs(t, logger.Append(log, []byte(`{}`)), errSyslogBackoff)
+	assert.Equal(t, 1, dials)
+	assert.Equal(t, syslogMinBackoff, logger.conns["testApp"].backoff)
+}
+
+func TestHandleSyslogOutput_RemoteTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	received := acceptOne(t, listener)
+
+	logger := &syslogAppender{config: &SyslogConfig{Facility: SyslogFacilityLocal0, Network: "tcp", Address: listener.Addr().String(), Format: SyslogFormatRFC5424}}
+	err = logger.Append(testSyslogLog(), []byte(`{"msg":"remote"}`))
 	assert.NoError(t, err)
+	assert.NoError(t, logger.Close())
+
+	assert.Contains(t, <-received, `<134>1 `)
 }
@@ -2,6 +2,16 @@
 
 package logger
 
-func (sl MangoLogger) handleSyslogOutput(log *StructuredLog, jsonOut []byte) error {
+import "fmt"
+
+// checkSyslogTransport rejects the local transports, there is no local syslog daemon on windows
+func checkSyslogTransport(config *SyslogConfig) error {
+	if !isNetworkSyslog(config.Network) {
+		return fmt.Errorf("%w - network %q: configure out.syslog.network as udp, tcp or tls with out.syslog.address on windows", ErrSyslogUnsupported, config.Network)
+	}
 	return nil
 }
+
+func dialSyslog(config *SyslogConfig, facility int, tag string) (syslogWriter, error) {
+	return dialNetworkSyslog(config, facility, tag)
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package logger
+
+import (
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleSyslogOutput_LocalUnsupported(t *testing.T) {
+	for _, network := range []string{"", "unixgram"} {
+		logger := &syslogAppender{config: &SyslogConfig{Facility: SyslogFacilityUser, Network: network}}
+		err := logger.Append(&StructuredLog{Level: slog.LevelInfo, Application: "testApp"}, []byte(`{}`))
+		assert.ErrorIs(t, err, ErrSyslogUnsupported)
+	}
+}
+
+func TestHandleSyslogOutput_RemoteUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	logger := &syslogAppender{config: &SyslogConfig{Facility: SyslogFacilityLocal0, Network: "udp", Address: listener.LocalAddr().String()}}
+	err = logger.Append(&StructuredLog{Level: slog.LevelInfo, Application: "testApp"}, []byte(`{"msg":"windows"}`))
+	assert.NoError(t, err)
+	assert.NoError(t, logger.Close())
+
+	buf := make([]byte, 4096)
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf[:n]), `{"msg":"windows"}`)
+}
@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syslogNetworkTimeout bounds dialing and writing to a remote collector
+const syslogNetworkTimeout = 5 * time.Second
+
+// rfc5424TimeFormat has the microsecond precision allowed by RFC 5424 TIME-SECFRAC (up to 6 digits)
+const rfc5424TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// isNetworkSyslog reports whether the network is one of the remote transports handled by netSyslogWriter
+func isNetworkSyslog(network string) bool {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "tls":
+		return true
+	default:
+		return false
+	}
+}
+
+// netSyslogWriter sends RFC 3164 or RFC 5424 messages to a remote collector over udp, tcp or tls
+type netSyslogWriter struct {
+	config   *SyslogConfig
+	conn     net.Conn
+	stream   bool
+	facility int
+	tag      string
+	hostname string
+}
+
+func dialNetworkSyslog(config *SyslogConfig, facility int, tag string) (syslogWriter, error) {
+	dialer := &net.Dialer{Timeout: syslogNetworkTimeout}
+	var conn net.Conn
+	var err error
+	if config.Network == "tls" {
+		var tlsConfig *tls.Config
+		tlsConfig, err = syslogTLSConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", config.Address, tlsConfig)
+	} else {
+		conn, err = dialer.Dial(config.Network, config.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+	return &netSyslogWriter{
+		config:   config,
+		conn:     conn,
+		stream:   !strings.HasPrefix(config.Network, "udp"),
+		facility: facility,
+		tag:      tag,
+		hostname: hostname,
+	}, nil
+}
+
+func syslogTLSConfig(config *SyslogConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.TLS == nil {
+		return tlsConfig, nil
+	}
+	tlsConfig.ServerName = config.TLS.ServerName
+	tlsConfig.InsecureSkipVerify = config.TLS.InsecureSkipVerify
+	if config.TLS.CAFile != "" {
+		pem, err := os.ReadFile(config.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read syslog tls ca-file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in syslog tls ca-file %s", config.TLS.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.TLS.CertFile != "" || config.TLS.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.TLS.CertFile, config.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load syslog tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func (w *netSyslogWriter) write(log *StructuredLog, msg []byte) error {
+	severity := syslogSeverity(log.Level)
+	var message string
+	if w.config.Format == SyslogFormatRFC5424 {
+		message = w.formatRFC5424(w.facility<<3|severity, log, msg)
+	} else {
+		message = w.formatRFC3164(w.facility<<3|severity, log, msg)
+	}
+
+	if w.stream {
+		if w.config.Framing == SyslogFramingNonTransparent {
+			message += "\n"
+		} else {
+			message = strconv.Itoa(len(message)) + " " + message
+		}
+	}
+
+	_ = w.conn.SetWriteDeadline(time.Now().Add(syslogNetworkTimeout))
+	_, err := w.conn.Write([]byte(message))
+	return err
+}
+
+// formatRFC3164 renders <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+func (w *netSyslogWriter) formatRFC3164(priority int, log *StructuredLog, msg []byte) string {
+	return fmt.Sprintf("<%d>%s %s %s[%d]: %s",
+		priority, logTime(log).Format(time.Stamp), syslogHeaderValue(w.hostname, 255), syslogHeaderValue(w.tag, 48), os.Getpid(), msg)
+}
+
+// formatRFC5424 renders <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD-ID key="value"...] MSG
+// The log type is used as MSGID and the mango contract fields as structured data
+func (w *netSyslogWriter) formatRFC5424(priority int, log *StructuredLog, msg []byte) string {
+	sdID := w.config.StructuredDataID
+	if sdID == "" {
+		sdID = DefaultSyslogStructuredDataID
+	}
+
+	var sd strings.Builder
+	sd.WriteString("[" + sdID)
+	params := [][2]string{
+		{"correlationid", log.Correlationid},
+		{"traceId", log.TraceId},
+		{"spanId", log.SpanId},
+		{"logId", log.LogId},
+		{"operation", log.Operation},
+		{"type", log.Type},
+	}
+	for _, field := range log.fields() {
+		// fields whose name is not a valid SD-NAME would make the whole message invalid
+		if value, ok := log.Fields[field.name]; ok && validSDName(field.name) {
+			params = append(params, [2]string{field.name, value})
+		}
+	}
+	for _, param := range params {
+		if param[1] != "" {
+			sd.WriteString(" " + param[0] + `="` + escapeSDParam(param[1]) + `"`)
+		}
+	}
+	sd.WriteString("]")
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		priority, logTime(log).Format(rfc5424TimeFormat), syslogHeaderValue(w.hostname, 255), syslogHeaderValue(w.tag, 48),
+		os.Getpid(), syslogHeaderValue(log.Type, 32), sd.String(), msg)
+}
+
+// validSDName reports whether the name is an RFC 5424 SD-NAME: 1 to 32 printable ASCII characters except '=', ' ', ']' and '"'
+func validSDName(name string) bool {
+	if name == "" || len(name) > 32 {
+		return false
+	}
+	for _, r := range name {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return false
+		}
+	}
+	return true
+}
+
+// logTime is the time of the log entry, or now if it has none
+func logTime(log *StructuredLog) time.Time {
+	if t, err := time.Parse(RFC3339NanoMC, log.Timestamp); err == nil {
+		return t
+	}
+	return time.Now()
+}
+
+// syslogHeaderValue keeps printable non-space ASCII (as required for header fields) up to maxLen, "-" when empty
+func syslogHeaderValue(value string, maxLen int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// escapeSDParam escapes '"', '\' and ']' in structured data parameter values
+func escapeSDParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+func (w *netSyslogWriter) Close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,231 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSyslogLog() *StructuredLog {
+	return &StructuredLog{
+		Timestamp:     time.Date(2025, 1, 15, 9, 53, 34, 0, time.UTC).Format(RFC3339NanoMC),
+		Level:         slog.LevelInfo,
+		Application:   "testApp",
+		Operation:     "op",
+		Type:          BusinessType,
+		Correlationid: `c"1]`,
+		LogId:         "l1",
+	}
+}
+
+// acceptOne accepts a single connection and returns everything it sends until closed
+func acceptOne(t *testing.T, listener net.Listener) <-chan string {
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if !assert.NoError(t, err) {
+			close(received)
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+	return received
+}
+
+// readOctetCounted reads one "LEN SP MSG" frame
+func readOctetCounted(t *testing.T, r *bufio.Reader) string {
+	length, err := r.ReadString(' ')
+	assert.NoError(t, err)
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	assert.NoError(t, err)
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	assert.NoError(t, err)
+	return string(msg)
+}
+
+func TestNetworkSyslog_UDP_RFC3164(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	config := &SyslogConfig{Facility: SyslogFacilityLocal0, Network: "udp", Address: listener.LocalAddr().String()}
+	writer, err := dialNetworkSyslog(config, syslogFacilityCodes[SyslogFacilityLocal0], "testApp")
+	assert.NoError(t, err)
+	defer func() { _ = writer.Close() }()
+
+	assert.NoError(t, writer.write(testSyslogLog(), []byte(`{"msg":"hello"}`)))
+
+	buf := make([]byte, 4096)
+	_ = listener.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	assert.NoError(t, err)
+	received := string(buf[:n])
+
+	assert.True(t, strings.HasPrefix(received, "<134>Jan 15 09:53:34 "), received)
+	assert.True(t, strings.HasSuffix(received, fmt.Sprintf(" testApp[%d]: {\"msg\":\"hello\"}", os.Getpid())), received)
+}
+
+func TestNetworkSyslog_TCP_RFC5424_OctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	received := acceptOne(t, listener)
+
+	config := &SyslogConfig{Network: "tcp", Address: listener.Addr().String(), Format: SyslogFormatRFC5424}
+	writer, err := dialNetworkSyslog(config, syslogFacilityCodes[SyslogFacilityUser], "test App")
+	assert.NoError(t, err)
+
+	log := testSyslogLog()
+	assert.NoError(t, writer.write(log, []byte(`{"msg":"first"}`)))
+	log.Level = slog.LevelError
+	log.Type = ""
+	assert.NoError(t, writer.write(log, []byte(`{"msg":"second"}`)))
+	assert.NoError(t, writer.Close())
+
+	r := bufio.NewReader(strings.NewReader(<-received))
+	first := readOctetCounted(t, r)
+	assert.True(t, strings.HasPrefix(first, "<14>1 2025-01-15T09:53:34.000000Z "), first)
+	assert.Contains(t, first, fmt.Sprintf(" test_App %d Business ", os.Getpid()))
+	assert.True(t, strings.HasSuffix(first, `[mango@32473 correlationid="c\"1\]" logId="l1" operation="op" type="Business"] {"msg":"first"}`), first)
+
+	second := readOctetCounted(t, r)
+	assert.True(t, strings.HasPrefix(second, "<11>1 "), second)
+	assert.True(t, strings.HasSuffix(second, fmt.Sprintf(` test_App %d - [mango@32473 correlationid="c\"1\]" logId="l1" operation="op"] {"msg":"second"}`, os.Getpid())), second)
+}
+
+func TestNetworkSyslog_TCP_NonTransparentFraming(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	received := acceptOne(t, listener)
+
+	config := &SyslogConfig{Network: "tcp", Address: listener.Addr().String(), Framing: SyslogFramingNonTransparent, StructuredDataID: "acme@1"}
+	writer, err := dialNetworkSyslog(config, syslogFacilityCodes[SyslogFacilityUser], "testApp")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.write(testSyslogLog(), []byte(`{"msg":"one"}`)))
+	assert.NoError(t, writer.write(testSyslogLog(), []byte(`{"msg":"two"}`)))
+	assert.NoError(t, writer.Close())
+
+	lines := strings.Split(strings.TrimSuffix(<-received, "\n"), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "<14>"))
+	assert.True(t, strings.HasSuffix(lines[1], `{"msg":"two"}`))
+}
+
+func TestNetworkSyslog_TLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12})
+	assert.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	received := acceptOne(t, listener)
+
+	config := &SyslogConfig{
+		Network: "tls",
+		Address: listener.Addr().String(),
+		Format:  SyslogFormatRFC5424,
+		TLS:     &SyslogTLSConfig{CAFile: certFile},
+	}
+	writer, err := dialNetworkSyslog(config, syslogFacilityCodes[SyslogFacilityLocal7], "testApp")
+	assert.NoError(t, err)
+	assert.NoError(t, writer.write(testSyslogLog(), []byte(`{"msg":"secure"}`)))
+	assert.NoError(t, writer.Close())
+
+	msg := readOctetCounted(t, bufio.NewReader(strings.NewReader(<-received)))
+	assert.True(t, strings.HasPrefix(msg, "<190>1 "), msg)
+	assert.True(t, strings.HasSuffix(msg, `{"msg":"secure"}`), msg)
+}
+
+func TestNetworkSyslog_TLSConfigErrors(t *testing.T) {
+	_, err := dialNetworkSyslog(&SyslogConfig{Network: "tls", Address: "127.0.0.1:1", TLS: &SyslogTLSConfig{CAFile: "missing.pem"}}, 1, "tag")
+	assert.ErrorContains(t, err, "ca-file")
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	assert.NoError(t, os.WriteFile(empty, []byte("not a cert"), 0o600))
+	_, err = dialNetworkSyslog(&SyslogConfig{Network: "tls", Address: "127.0.0.1:1", TLS: &SyslogTLSConfig{CAFile: empty}}, 1, "tag")
+	assert.ErrorContains(t, err, "no certificate found")
+
+	_, err = dialNetworkSyslog(&SyslogConfig{Network: "tls", Address: "127.0.0.1:1", TLS: &SyslogTLSConfig{CertFile: "missing.pem", KeyFile: "missing.key"}}, 1, "tag")
+	assert.ErrorContains(t, err, "client certificate")
+}
+
+func TestNetworkSyslog_RFC5424Limits(t *testing.T) {
+	writer := &netSyslogWriter{config: &SyslogConfig{}, hostname: "host", tag: "app"}
+	log := testSyslogLog()
+	log.Timestamp = "2025-01-15T09:53:34.123456789Z"
+	log.Fields = map[string]string{
+		"tenant":                              "acme",
+		"with space":                          "x",
+		"a=b":                                 "x",
+		"bracket]":                            "x",
+		`quote"`:                              "x",
+		"a-name-longer-than-thirty-two-chars": "x",
+	}
+
+	message := writer.formatRFC5424(14, log, []byte(`{}`))
+	// at most 6 fractional digits
+	assert.True(t, strings.HasPrefix(message, "<14>1 2025-01-15T09:53:34.123456Z "), message)
+	assert.Contains(t, message, `type="Business" tenant="acme"] {}`)
+	assert.NotContains(t, message, `="x"`)
+
+	assert.True(t, validSDName("user.id"))
+	assert.False(t, validSDName(""))
+	assert.False(t, validSDName("tab\tname"))
+}
+
+func TestSyslogHeaderValue(t *testing.T) {
+	assert.Equal(t, "-", syslogHeaderValue("", 48))
+	assert.Equal(t, "my_app", syslogHeaderValue("my app", 48))
+	assert.Equal(t, "abc", syslogHeaderValue("abcdef", 3))
+}
+
+// writeSelfSignedCert creates a certificate for 127.0.0.1 and returns the cert and key file paths
+func writeSelfSignedCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mango-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600))
+	return certFile, keyFile
+}
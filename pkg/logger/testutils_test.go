@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newBufferedLogger creates a logger writing to the returned buffer, closed at the end of the test
+// A config without Out gets an enabled output with only the buffer appender
+func newBufferedLogger(t *testing.T, config *LogConfig, level slog.Level, formatter Formatter) (*MangoLogger, *bytes.Buffer) {
+	if config.Out == nil {
+		config.Out = &OutConfig{Enabled: true}
+	}
+	logger, err := New(config)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	logger.AddAppender(NewWriterAppender(&buf, level, formatter))
+	t.Cleanup(func() { _ = logger.Close(context.Background()) })
+	return logger, &buf
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceContext is the W3C trace context a log entry belongs to
+type TraceContext struct {
+	// TraceId is the 32 lowercase hex characters trace-id
+	TraceId string
+
+	// SpanId is the 16 lowercase hex characters parent-id (span-id)
+	SpanId string
+
+	// TraceFlags are the 2 hex characters trace-flags, 01 when sampled
+	TraceFlags string
+}
+
+// ParseTraceparent parses a W3C traceparent header value: version-traceid-spanid-flags, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+// Versions above 00 are accepted as long as they start with the version 00 fields, as the specification requires
+func ParseTraceparent(traceparent string) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, fmt.Errorf("%w %q: expected version-traceid-spanid-flags", errInvalidTraceparent, traceparent)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	switch {
+	case !isLowerHex(version, 2) || version == "ff":
+		return TraceContext{}, fmt.Errorf("%w %q: bad version", errInvalidTraceparent, traceparent)
+	case version == "00" && len(parts) != 4:
+		return TraceContext{}, fmt.Errorf("%w %q: unexpected fields for version 00", errInvalidTraceparent, traceparent)
+	case !isLowerHex(traceID, 32) || traceID == strings.Repeat("0", 32):
+		return TraceContext{}, fmt.Errorf("%w %q: bad trace-id", errInvalidTraceparent, traceparent)
+	case !isLowerHex(spanID, 16) || spanID == strings.Repeat("0", 16):
+		return TraceContext{}, fmt.Errorf("%w %q: bad parent-id", errInvalidTraceparent, traceparent)
+	case !isLowerHex(flags, 2):
+		return TraceContext{}, fmt.Errorf("%w %q: bad trace-flags", errInvalidTraceparent, traceparent)
+	}
+	return TraceContext{TraceId: traceID, SpanId: spanID, TraceFlags: flags}, nil
+}
+
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// traceContextFrom the OpenTelemetry span of the context, or its TRACEPARENT value when there is no valid span
+func traceContextFrom(ctx context.Context) (TraceContext, bool) {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		return TraceContext{
+			TraceId:    spanContext.TraceID().String(),
+			SpanId:     spanContext.SpanID().String(),
+			TraceFlags: spanContext.TraceFlags().String(),
+		}, true
+	}
+	if traceparent, ok := ctx.Value(TRACEPARENT).(string); ok {
+		if traceContext, err := ParseTraceparent(traceparent); err == nil {
+			return traceContext, true
+		}
+	}
+	return TraceContext{}, false
+}
+
+// setTraceContext copies the trace context of ctx (if any) into the log
+func setTraceContext(ctx context.Context, logOutput *StructuredLog) {
+	if traceContext, ok := traceContextFrom(ctx); ok {
+		logOutput.TraceId = traceContext.TraceId
+		logOutput.SpanId = traceContext.SpanId
+		logOutput.TraceFlags = traceContext.TraceFlags
+	}
+}
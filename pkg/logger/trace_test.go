@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	traceContext, err := ParseTraceparent(testTraceparent)
+	assert.NoError(t, err)
+	assert.Equal(t, TraceContext{TraceId: "4bf92f3577b34da6a3ce929d0e0e4736", SpanId: "00f067aa0ba902b7", TraceFlags: "01"}, traceContext)
+
+	// future versions may append fields
+	_, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.NoError(t, err)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+	} {
+		_, err := ParseTraceparent(invalid)
+		assert.ErrorIs(t, err, errInvalidTraceparent, invalid)
+	}
+}
+
+func TestTraceContextFrom(t *testing.T) {
+	_, ok := traceContextFrom(context.Background())
+	assert.False(t, ok)
+
+	_, ok = traceContextFrom(context.WithValue(context.Background(), TRACEPARENT, "garbage"))
+	assert.False(t, ok)
+
+	ctx := context.WithValue(context.Background(), TRACEPARENT, testTraceparent)
+	traceContext, ok := traceContextFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceContext.TraceId)
+
+	// an OpenTelemetry span takes precedence over the traceparent value
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01, 0x02},
+		SpanID:  trace.SpanID{0x03},
+	}))
+	traceContext, ok = traceContextFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, TraceContext{TraceId: "01020000000000000000000000000000", SpanId: "0300000000000000", TraceFlags: "00"}, traceContext)
+}
+
+func TestMangoLogger_TraceCorrelation(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "traced", 0)
+	ctx := context.WithValue(context.Background(), OPERATION, "op1")
+	ctx = context.WithValue(ctx, TYPE, BusinessType)
+	ctx = context.WithValue(ctx, APPLICATION, "app")
+
+	logger := newTestLogger(true, false, false, false)
+	logOutput, err := logger.buildLog(ctx, record)
+	assert.NoError(t, err)
+	b, _ := json.Marshal(logOutput)
+	assert.NotContains(t, string(b), "traceId")
+
+	traced := context.WithValue(ctx, TRACEPARENT, testTraceparent)
+	logOutput, err = logger.buildLog(traced, record)
+	assert.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", logOutput.TraceId)
+	assert.Equal(t, "00f067aa0ba902b7", logOutput.SpanId)
+	assert.Equal(t, "01", logOutput.TraceFlags)
+	assert.Empty(t, logOutput.Correlationid)
+
+	// the correlation settings are fixed when the logger is created
+	logger.Config.MangoConfig.CorrelationId.FromTraceId = true
+	logger = NewMangoLogger(logger.Config)
+	logOutput, err = logger.buildLog(traced, record)
+	assert.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", logOutput.Correlationid)
+
+	// a correlation id in context wins
+	logOutput, err = logger.buildLog(WithCorrelationID(traced, "corr"), record)
+	assert.NoError(t, err)
+	assert.Equal(t, "corr", logOutput.Correlationid)
+}
+
+func TestMangoLogger_TraceIdSatisfiesStrictCorrelation(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "traced", 0)
+	ctx := context.WithValue(context.Background(), OPERATION, "op1")
+	ctx = context.WithValue(ctx, TYPE, BusinessType)
+	ctx = context.WithValue(ctx, APPLICATION, "app")
+	ctx = context.WithValue(ctx, TRACEPARENT, testTraceparent)
+
+	logger := newTestLogger(true, false, true, true)
+	logger.Config.MangoConfig.CorrelationId.FromTraceId = true
+	logger = NewMangoLogger(logger.Config)
+	logOutput, err := logger.buildLog(ctx, record)
+	assert.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", logOutput.Correlationid)
+}
@@ -21,14 +21,19 @@ const (
 	OPERATION      ctxKey = "operation"
 )
 
-// ALLOWED_TYPES are the allowed values for TYPE
+// TRACEPARENT is the context key of a W3C traceparent header value, used for trace correlation when the context has no OpenTelemetry span
+const TRACEPARENT ctxKey = "traceparent"
+
+// ALLOWED_TYPES are the allowed values for TYPE, unless MangoConfig.AllowedTypes is set
+// It is copied when a logger is created, later changes only apply to the loggers created afterwards
 var ALLOWED_TYPES = []string{
 	BusinessType,
 	SecurityType,
 	PerformanceType,
 }
 
-// REQUIRED_FIELDS are the fields checked against when MangoConfig.Strict is set
+// REQUIRED_FIELDS are the fields checked against when MangoConfig.Strict is set, CORRELATION_ID being added per logger with CorrelationIdConfig.Strict
+// It is copied when a logger is created, later changes only apply to the loggers created afterwards
 var REQUIRED_FIELDS = []ctxKey{
 	TYPE,
 	APPLICATION,